@@ -3,11 +3,13 @@ module github.com/jerome-quere/grpc-cli
 go 1.16
 
 require (
-	github.com/sirupsen/logrus v1.2.0 // indirect
+	github.com/jhump/protoreflect v1.9.0
+	github.com/sirupsen/logrus v1.2.0
 	github.com/spf13/cobra v1.1.3
 	github.com/spf13/pflag v1.0.5
 	github.com/stretchr/testify v1.7.0
-	google.golang.org/grpc v1.21.1
+	google.golang.org/genproto v0.0.0-20200526211855-cb27e3aa2013
+	google.golang.org/grpc v1.27.0
 	google.golang.org/protobuf v1.26.0
 	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
 )
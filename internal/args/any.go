@@ -0,0 +1,91 @@
+package args
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+const (
+	anyFullName      = protoreflect.FullName("google.protobuf.Any")
+	anyTypeWord      = "@type"
+	anyTypeURLPrefix = "type.googleapis.com/"
+)
+
+// isAnyTypeArg returns true if an arg sets the type of a google.protobuf.Any (e.g. payload.@type=my.pkg.Foo).
+func isAnyTypeArg(argName string) bool {
+	return argName == anyTypeWord || strings.HasSuffix(argName, "."+anyTypeWord)
+}
+
+func (o UnmarshalOptions) resolver() interface {
+	protoregistry.MessageTypeResolver
+	protoregistry.ExtensionTypeResolver
+} {
+	if o.Resolver == nil {
+		return protoregistry.GlobalTypes
+	}
+	return o.Resolver
+}
+
+// unmarshalAny sets a field of a google.protobuf.Any.
+// The type must be set first using @type. Each field is then set by unpacking the message,
+// setting the field and packing the message again.
+func (o UnmarshalOptions) unmarshalAny(dest protoreflect.Message, argNameWords []string, value string) error {
+	typeURLField := dest.Descriptor().Fields().ByName("type_url")
+	valueField := dest.Descriptor().Fields().ByName("value")
+
+	if argNameWords[0] == anyTypeWord {
+		if len(argNameWords) > 1 {
+			return fmt.Errorf("cannot set nested field %s", argNameWords[1])
+		}
+
+		typeURL := value
+		if !strings.Contains(typeURL, "/") {
+			typeURL = anyTypeURLPrefix + typeURL
+		}
+		_, err := o.resolver().FindMessageByURL(typeURL)
+		if err != nil {
+			return fmt.Errorf("cannot resolve type %s: %w", value, err)
+		}
+
+		dest.Set(typeURLField, protoreflect.ValueOfString(typeURL))
+		dest.Clear(valueField)
+		return nil
+	}
+
+	typeURL := dest.Get(typeURLField).String()
+	if typeURL == "" {
+		return fmt.Errorf("type of google.protobuf.Any must be set using %s", anyTypeWord)
+	}
+	messageType, err := o.resolver().FindMessageByURL(typeURL)
+	if err != nil {
+		return fmt.Errorf("cannot resolve type %s: %w", typeURL, err)
+	}
+
+	message := messageType.New()
+	err = proto.UnmarshalOptions{Resolver: o.resolver()}.Unmarshal(dest.Get(valueField).Bytes(), message.Interface())
+	if err != nil {
+		return fmt.Errorf("cannot unpack %s: %w", typeURL, err)
+	}
+
+	// Same as protojson: well-known types are set using the value key (e.g. payload.value=1.5s)
+	_, hasUnmarshalFunc := unmarshalFuncs[message.Descriptor().FullName()]
+	if hasUnmarshalFunc && len(argNameWords) == 1 && argNameWords[0] == "value" {
+		argNameWords = nil
+	}
+
+	err = o.unmarshalMessage(message, argNameWords, value)
+	if err != nil {
+		return err
+	}
+
+	raw, err := proto.MarshalOptions{Deterministic: true}.Marshal(message.Interface())
+	if err != nil {
+		return fmt.Errorf("cannot pack %s: %w", typeURL, err)
+	}
+	dest.Set(valueField, protoreflect.ValueOfBytes(raw))
+	return nil
+}
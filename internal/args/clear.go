@@ -0,0 +1,112 @@
+package args
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	// nullValue clears the targeted field the same way a JSON null does (e.g. wrapper_str=null).
+	nullValue = "null"
+	// clearPrefix clears a field without setting its parents (e.g. -nested.str).
+	clearPrefix = "-"
+)
+
+// isClearArg returns true if an arg clears a field using the clear prefix (e.g. -wrapper_str).
+func isClearArg(argName string) bool {
+	return strings.HasPrefix(argName, clearPrefix) && len(argName) > len(clearPrefix)
+}
+
+// isNullable returns true if a null value clears the field instead of being parsed.
+// Strings and bytes keep null as a literal value and google.protobuf.Value uses it as a JSON null.
+func isNullable(field protoreflect.FieldDescriptor) bool {
+	if field.IsList() || field.IsMap() {
+		return true
+	}
+	switch field.Kind() {
+	case protoreflect.StringKind, protoreflect.BytesKind:
+		return false
+	case protoreflect.MessageKind:
+		return field.Message().FullName() != "google.protobuf.Value"
+	}
+	return true
+}
+
+// clearField clears a field of dest. If the field is part of a oneof, another member can then be set.
+func (o UnmarshalOptions) clearField(dest protoreflect.Message, field protoreflect.FieldDescriptor) {
+	if oneof := field.ContainingOneof(); oneof != nil && !oneof.IsSynthetic() {
+		key := oneofKey{message: dest, oneof: oneof.FullName()}
+		if o.state.oneofFields[key] == field.Name() {
+			delete(o.state.oneofFields, key)
+		}
+	}
+	dest.Clear(field)
+}
+
+// clear clears the field targeted by an arg name. Unlike null values, parent messages that are not set are left untouched.
+// Fields are looked up even if their parent is not set so that unknown fields are reported.
+func (o UnmarshalOptions) clear(dest protoreflect.Message, argNameWords []string) error {
+	desc := dest.Descriptor()
+	if _, hasUnmarshalFunc := unmarshalFuncs[desc.FullName()]; hasUnmarshalFunc || desc.FullName() == anyFullName {
+		return fmt.Errorf("cannot clear nested field %s of %s", argNameWords[0], desc.FullName())
+	}
+
+	field, err := FindField(desc, argNameWords[0])
+	if err != nil {
+		return err
+	}
+	if len(argNameWords) == 1 {
+		if dest.Has(field) {
+			o.clearField(dest, field)
+		}
+		return nil
+	}
+
+	switch {
+	case field.IsList():
+		index, err := strconv.ParseUint(argNameWords[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid index array %s", argNameWords[1])
+		}
+		if len(argNameWords) == 2 {
+			return fmt.Errorf("cannot clear element %d of %s, only whole lists can be cleared", index, field.Name())
+		}
+		if field.Kind() != protoreflect.MessageKind {
+			return fmt.Errorf("cannot clear nested field %s", argNameWords[2])
+		}
+		list := dest.Get(field).List()
+		if int(index) >= list.Len() {
+			return o.clear(list.NewElement().Message(), argNameWords[2:])
+		}
+		return o.clear(list.Get(int(index)).Message(), argNameWords[2:])
+
+	case field.IsMap():
+		key, err := unmarshalMapKey(field.MapKey(), argNameWords[1])
+		if err != nil {
+			return err
+		}
+		m := dest.Get(field).Map()
+		if len(argNameWords) == 2 {
+			if m.Has(key) {
+				dest.Mutable(field).Map().Clear(key)
+			}
+			return nil
+		}
+		if field.MapValue().Kind() != protoreflect.MessageKind {
+			return fmt.Errorf("cannot clear nested field %s", argNameWords[2])
+		}
+		if !m.Has(key) {
+			return o.clear(m.NewValue().Message(), argNameWords[2:])
+		}
+		return o.clear(m.Get(key).Message(), argNameWords[2:])
+
+	case field.Kind() == protoreflect.MessageKind:
+		return o.clear(dest.Get(field).Message(), argNameWords[1:])
+
+	default:
+		return fmt.Errorf("cannot clear nested field %s", argNameWords[1])
+	}
+}
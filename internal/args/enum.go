@@ -0,0 +1,78 @@
+package args
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// unmarshalEnum parses an enum value. Values are matched in this order:
+// - exact name: STATUS_ACTIVE
+// - number: 1
+// - case-insensitive name: status_active
+// - case-insensitive name without the common prefix: active
+func unmarshalEnum(enum protoreflect.EnumDescriptor, value string) (protoreflect.EnumNumber, error) {
+	values := enum.Values()
+	if enumValue := values.ByName(protoreflect.Name(value)); enumValue != nil {
+		return enumValue.Number(), nil
+	}
+
+	// Same as protojson, numbers that are not defined in the enum are accepted.
+	if number, err := strconv.ParseInt(value, 10, 32); err == nil {
+		return protoreflect.EnumNumber(number), nil
+	}
+
+	prefix := enumValuePrefix(enum)
+	for i := 0; i < values.Len(); i++ {
+		name := string(values.Get(i).Name())
+		if strings.EqualFold(name, value) || (prefix != "" && strings.EqualFold(strings.TrimPrefix(name, prefix), value)) {
+			return values.Get(i).Number(), nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown enum value %s, expected one of %s", value, strings.Join(EnumValueNames(enum), ", "))
+}
+
+// EnumValueNames returns the names of the values of an enum as displayed in usage and autocompletion.
+// The prefix shared by all values (e.g. STATUS_ for STATUS_ACTIVE and STATUS_DELETED) is removed.
+func EnumValueNames(enum protoreflect.EnumDescriptor) []string {
+	prefix := enumValuePrefix(enum)
+	names := []string(nil)
+	for i := 0; i < enum.Values().Len(); i++ {
+		names = append(names, strings.TrimPrefix(string(enum.Values().Get(i).Name()), prefix))
+	}
+	return names
+}
+
+// enumValuePrefix returns the prefix, ending with an underscore, shared by all values of an enum.
+// No prefix is returned if removing it would make a value empty or start with a digit,
+// as short names must remain distinct from numbers.
+func enumValuePrefix(enum protoreflect.EnumDescriptor) string {
+	values := enum.Values()
+	if values.Len() < 2 {
+		return ""
+	}
+
+	prefix := string(values.Get(0).Name())
+	for i := 1; i < values.Len(); i++ {
+		name := string(values.Get(i).Name())
+		for !strings.HasPrefix(name, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	prefix = prefix[:strings.LastIndex(prefix, "_")+1]
+	if prefix == "" {
+		return ""
+	}
+
+	for i := 0; i < values.Len(); i++ {
+		short := strings.TrimPrefix(string(values.Get(i).Name()), prefix)
+		if short == "" || unicode.IsDigit(rune(short[0])) {
+			return ""
+		}
+	}
+	return prefix
+}
@@ -0,0 +1,44 @@
+package args
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func Test_enumValuePrefix(t *testing.T) {
+	tests := []struct {
+		values   []string
+		expected string
+	}{
+		{[]string{"STATUS_UNKNOWN", "STATUS_ACTIVE", "STATUS_DELETED"}, "STATUS_"},
+		{[]string{"STATUS_UNKNOWN", "STATUS_ACTIVE_SOON", "STATUS_ACTIVE"}, "STATUS_"},
+		{[]string{"ORDER_STATUS_UNKNOWN", "ORDER_STATUS_PAID"}, "ORDER_STATUS_"},
+		{[]string{"UNKNOWN", "ACTIVE"}, ""},
+		{[]string{"STATUS_UNKNOWN", "ACTIVE"}, ""},
+		{[]string{"PRIORITY_UNKNOWN", "PRIORITY_1", "PRIORITY_2"}, ""},
+		{[]string{"STATUS_UNKNOWN"}, ""},
+	}
+	for _, test := range tests {
+		t.Run(strings.Join(test.values, ","), func(t *testing.T) {
+			enumProto := &descriptorpb.EnumDescriptorProto{Name: proto.String("Enum")}
+			for i, value := range test.values {
+				enumProto.Value = append(enumProto.Value, &descriptorpb.EnumValueDescriptorProto{Name: proto.String(value), Number: proto.Int32(int32(i))})
+			}
+			file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+				Name:     proto.String("enum.proto"),
+				Package:  proto.String("test"),
+				Syntax:   proto.String("proto3"),
+				EnumType: []*descriptorpb.EnumDescriptorProto{enumProto},
+			}, nil)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expected, enumValuePrefix(file.Enums().Get(0)))
+		})
+	}
+}
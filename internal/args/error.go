@@ -1,23 +1,83 @@
 package args
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
 
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ArgErrors groups all the errors that occurred while unmarshaling args, ordered by position.
+type ArgErrors []error
+
+func (e ArgErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	messages := []string(nil)
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return fmt.Sprintf("%d invalid args:\n  %s", len(e), strings.Join(messages, "\n  "))
+}
+
+// Unwrap returns the first error.
+func (e ArgErrors) Unwrap() error {
+	return e[0]
+}
+
+// DuplicateArgError is returned when the same field is set multiple times.
+// OtherArgName may differ from ArgName when an alias of the field is used (e.g. its json name).
 type DuplicateArgError struct {
-	ArgName string
+	ArgName       string
+	Position      int
+	OtherArgName  string
+	OtherPosition int
 }
 
 func (e *DuplicateArgError) Error() string {
-	return fmt.Sprintf("duplicate arg %s", e.ArgName)
+	return fmt.Sprintf("duplicate arg #%d %s, already set by arg #%d %s", e.Position, e.ArgName, e.OtherPosition, e.OtherArgName)
 }
 
 type UnmarshalArgError struct {
 	ArgName  string
 	ArgValue string
+	// Position of the arg, starting at 1.
+	Position int
 	Err      error
 }
 
 func (e *UnmarshalArgError) Error() string {
-	return fmt.Sprintf("unmarshal error for arg %s with value %s: %s", e.ArgName, e.ArgValue, e.Err)
+	return fmt.Sprintf("unmarshal error for arg #%d %s with value %s: %s", e.Position, e.ArgName, e.ArgValue, e.Err)
+}
+
+func (e *UnmarshalArgError) Unwrap() error {
+	return e.Err
+}
+
+// AmbiguousArgError is returned when an arg name matches multiple fields case-insensitively.
+type AmbiguousArgError struct {
+	ArgName string
+	Fields  []protoreflect.Name
+}
+
+func (e *AmbiguousArgError) Error() string {
+	fields := []string(nil)
+	for _, field := range e.Fields {
+		fields = append(fields, string(field))
+	}
+	return fmt.Sprintf("ambiguous arg %s, it matches fields %s", e.ArgName, strings.Join(fields, ", "))
+}
+
+// OneofConflictError is returned when multiple fields of the same oneof are set.
+type OneofConflictError struct {
+	Oneof      protoreflect.FullName
+	Field      protoreflect.Name
+	OtherField protoreflect.Name
+}
+
+func (e *OneofConflictError) Error() string {
+	return fmt.Sprintf("cannot set %s as %s is already set, they are both part of oneof %s", e.Field, e.OtherField, e.Oneof)
 }
 
 type CannotParseBoolError struct {
@@ -27,3 +87,15 @@ type CannotParseBoolError struct {
 func (e *CannotParseBoolError) Error() string {
 	return fmt.Sprintf("%s is not a valid boolean value", e.Value)
 }
+
+// errorPosition returns the position of the arg that caused an error.
+func errorPosition(err error) int {
+	switch err := err.(type) {
+	case *UnmarshalArgError:
+		return err.Position
+	case *DuplicateArgError:
+		return err.Position
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,310 @@
+package args
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// MarshalOptions is a configurable args marshaler.
+type MarshalOptions struct {
+	// Resolver is used to resolve google.protobuf.Any types.
+	// If nil, protoregistry.GlobalTypes is used.
+	Resolver interface {
+		protoregistry.MessageTypeResolver
+		protoregistry.ExtensionTypeResolver
+	}
+
+	// Naming is the naming style of the args. Proto names are used by default.
+	Naming NamingStyle
+}
+
+// Marshal converts a message to args using default options.
+// Fields that cannot be represented as args (e.g. google.protobuf.Any with an unknown type) are skipped,
+// use MarshalOptions to get an error instead.
+func Marshal(msg protoreflect.Message) []string {
+	args, _ := MarshalOptions{}.Marshal(msg)
+	return args
+}
+
+// Marshal converts a message to the args that Unmarshal parses back to the same message.
+// Fields are marshaled in the order they are defined, lists use explicit indexes and map entries are sorted by key.
+func (o MarshalOptions) Marshal(msg protoreflect.Message) ([]string, error) {
+	if _, hasMarshalFunc := marshalFuncs[msg.Descriptor().FullName()]; hasMarshalFunc && msg.Descriptor().Fields().Len() > 0 {
+		return nil, fmt.Errorf("cannot marshal %s as args", msg.Descriptor().FullName())
+	}
+
+	m := &marshaler{options: o}
+	m.marshalMessage("", msg)
+	return m.args, m.err
+}
+
+// marshaler accumulates args. The first error is kept and marshaling goes on so the other fields are not lost.
+type marshaler struct {
+	options  MarshalOptions
+	args     []string
+	err      error
+	failures int
+}
+
+func (m *marshaler) add(argName string, value string) {
+	m.args = append(m.args, argName+"="+value)
+}
+
+func (m *marshaler) fail(err error) {
+	m.failures++
+	if m.err == nil {
+		m.err = err
+	}
+}
+
+func (m *marshaler) resolver() interface {
+	protoregistry.MessageTypeResolver
+	protoregistry.ExtensionTypeResolver
+} {
+	return UnmarshalOptions{Resolver: m.options.Resolver}.resolver()
+}
+
+// marshalMessage marshals all populated fields of msg. prefix is the arg name of msg followed by a dot.
+func (m *marshaler) marshalMessage(prefix string, msg protoreflect.Message) {
+	if msg.Descriptor().FullName() == anyFullName {
+		m.marshalAny(prefix, msg)
+		return
+	}
+
+	fields := msg.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if !msg.Has(field) {
+			continue
+		}
+		argName := prefix + ArgName(field, m.options.Naming)
+		value := msg.Get(field)
+
+		switch {
+		case field.IsList():
+			list := value.List()
+			for j := 0; j < list.Len(); j++ {
+				m.marshalValue(argName+"."+strconv.Itoa(j), field, list.Get(j))
+			}
+		case field.IsMap():
+			m.marshalMap(argName, msg, field)
+		default:
+			m.marshalValue(argName, field, value)
+		}
+	}
+}
+
+// marshalValue marshals a single value. Messages without any populated field only set their presence (e.g. nested=).
+func (m *marshaler) marshalValue(argName string, field protoreflect.FieldDescriptor, value protoreflect.Value) {
+	if field.Kind() != protoreflect.MessageKind {
+		m.add(argName, marshalScalar(field, value))
+		return
+	}
+
+	msg := value.Message()
+	if marshal, hasMarshalFunc := marshalFuncs[msg.Descriptor().FullName()]; hasMarshalFunc {
+		str, err := marshal(m, msg)
+		if err != nil {
+			m.fail(fmt.Errorf("cannot marshal %s: %w", argName, err))
+			return
+		}
+		// A singular field set to null would be cleared instead.
+		if str == nullValue && !field.IsList() && isNullable(field) {
+			m.fail(fmt.Errorf("cannot marshal %s: a %s set to null cannot be represented as an arg", argName, msg.Descriptor().FullName()))
+			return
+		}
+		m.add(argName, str)
+		return
+	}
+
+	count, failures := len(m.args), m.failures
+	m.marshalMessage(argName+".", msg)
+	if len(m.args) == count && m.failures == failures {
+		m.add(argName, "")
+	}
+}
+
+// marshalMap marshals map entries sorted by key.
+// Entries whose key cannot be part of an arg name (e.g. a key containing a dot) are grouped in a single inline JSON arg.
+func (m *marshaler) marshalMap(argName string, msg protoreflect.Message, field protoreflect.FieldDescriptor) {
+	entries := msg.Get(field).Map()
+	keys := []protoreflect.MapKey(nil)
+	entries.Range(func(key protoreflect.MapKey, _ protoreflect.Value) bool {
+		keys = append(keys, key)
+		return true
+	})
+	sort.Slice(keys, func(i, j int) bool {
+		return lessMapKey(keys[i], keys[j])
+	})
+
+	inline := msg.New()
+	inlineEntries := inline.Mutable(field).Map()
+	for _, key := range keys {
+		if !isValidMapKeyWord(key.String()) {
+			inlineEntries.Set(key, entries.Get(key))
+			continue
+		}
+		m.marshalValue(argName+"."+key.String(), field.MapValue(), entries.Get(key))
+	}
+	if inlineEntries.Len() == 0 {
+		return
+	}
+
+	raw, err := protojson.MarshalOptions{Resolver: m.resolver()}.Marshal(inline.Interface())
+	if err != nil {
+		m.fail(fmt.Errorf("cannot marshal %s: %w", argName, err))
+		return
+	}
+	inlineFields := map[string]json.RawMessage{}
+	err = json.Unmarshal(raw, &inlineFields)
+	if err != nil {
+		m.fail(fmt.Errorf("cannot marshal %s: %w", argName, err))
+		return
+	}
+	// protojson randomly adds spaces to its output, compacting it makes args stable.
+	compact := bytes.Buffer{}
+	err = json.Compact(&compact, inlineFields[field.JSONName()])
+	if err != nil {
+		m.fail(fmt.Errorf("cannot marshal %s: %w", argName, err))
+		return
+	}
+	m.add(argName, compact.String())
+}
+
+// marshalAny marshals the type of a google.protobuf.Any followed by the fields of the packed message.
+func (m *marshaler) marshalAny(prefix string, msg protoreflect.Message) {
+	typeURL := msg.Get(msg.Descriptor().Fields().ByName("type_url")).String()
+	value := msg.Get(msg.Descriptor().Fields().ByName("value")).Bytes()
+	if typeURL == "" && len(value) == 0 {
+		return
+	}
+
+	messageType, err := m.resolver().FindMessageByURL(typeURL)
+	if err != nil {
+		m.fail(fmt.Errorf("cannot resolve type %s: %w", typeURL, err))
+		return
+	}
+	packed := messageType.New()
+	err = proto.UnmarshalOptions{Resolver: m.resolver()}.Unmarshal(value, packed.Interface())
+	if err != nil {
+		m.fail(fmt.Errorf("cannot unpack %s: %w", typeURL, err))
+		return
+	}
+
+	m.add(prefix+anyTypeWord, strings.TrimPrefix(typeURL, anyTypeURLPrefix))
+
+	// Same as protojson: well-known types are set using the value key (e.g. payload.value=1.5s)
+	if marshal, hasMarshalFunc := marshalFuncs[packed.Descriptor().FullName()]; hasMarshalFunc {
+		str, err := marshal(m, packed)
+		if err != nil {
+			m.fail(fmt.Errorf("cannot marshal %svalue: %w", prefix, err))
+			return
+		}
+		m.add(prefix+"value", str)
+		return
+	}
+	m.marshalMessage(prefix, packed)
+}
+
+// isValidMapKeyWord returns true if a map key can be used as a word of an arg name.
+func isValidMapKeyWord(key string) bool {
+	return !strings.ContainsAny(key, ".=") && key != appendIndex && !strings.HasSuffix(key, "+")
+}
+
+func lessMapKey(a, b protoreflect.MapKey) bool {
+	switch a.Interface().(type) {
+	case bool:
+		return !a.Bool() && b.Bool()
+	case int32, int64:
+		return a.Int() < b.Int()
+	case uint32, uint64:
+		return a.Uint() < b.Uint()
+	default:
+		return a.String() < b.String()
+	}
+}
+
+// marshalScalar formats a scalar or an enum value the way unmarshalScalar and unmarshalEnum parse it.
+// Bytes use hex as base64 values could be mistaken for hex or file paths.
+func marshalScalar(field protoreflect.FieldDescriptor, value protoreflect.Value) string {
+	switch field.Kind() {
+	case protoreflect.EnumKind:
+		if enumValue := field.Enum().Values().ByNumber(value.Enum()); enumValue != nil {
+			return string(enumValue.Name())
+		}
+		return strconv.FormatInt(int64(value.Enum()), 10)
+	case protoreflect.FloatKind:
+		return strconv.FormatFloat(value.Float(), 'g', -1, 32)
+	case protoreflect.DoubleKind:
+		return strconv.FormatFloat(value.Float(), 'g', -1, 64)
+	case protoreflect.BytesKind:
+		return "0x" + hex.EncodeToString(value.Bytes())
+	default:
+		return value.String()
+	}
+}
+
+// marshalFunc formats a well-known type as a single arg value.
+type marshalFunc func(m *marshaler, msg protoreflect.Message) (string, error)
+
+var marshalFuncs = map[protoreflect.FullName]marshalFunc{
+	"google.protobuf.DoubleValue": marshalWrapper,
+	"google.protobuf.FloatValue":  marshalWrapper,
+	"google.protobuf.Int64Value":  marshalWrapper,
+	"google.protobuf.UInt64Value": marshalWrapper,
+	"google.protobuf.Int32Value":  marshalWrapper,
+	"google.protobuf.UInt32Value": marshalWrapper,
+	"google.protobuf.BoolValue":   marshalWrapper,
+	"google.protobuf.StringValue": marshalWrapper,
+	"google.protobuf.BytesValue":  marshalWrapper,
+	"google.protobuf.Timestamp":   marshalJSON,
+	"google.protobuf.Duration":    marshalJSON,
+	"google.protobuf.FieldMask": func(_ *marshaler, msg protoreflect.Message) (string, error) {
+		paths := msg.Get(msg.Descriptor().Fields().ByName("paths")).List()
+		strs := []string(nil)
+		for i := 0; i < paths.Len(); i++ {
+			strs = append(strs, paths.Get(i).String())
+		}
+		return strings.Join(strs, ","), nil
+	},
+	"google.protobuf.Empty":     marshalJSON,
+	"google.protobuf.Struct":    marshalJSON,
+	"google.protobuf.ListValue": marshalJSON,
+	"google.protobuf.Value":     marshalJSON,
+}
+
+func marshalWrapper(_ *marshaler, msg protoreflect.Message) (string, error) {
+	field := msg.Descriptor().Fields().ByName("value")
+	return marshalScalar(field, msg.Get(field)), nil
+}
+
+// marshalJSON formats a message using its protojson representation. JSON strings are unquoted (e.g. 1.5s)
+// except for google.protobuf.Value where they must be distinguished from other JSON values.
+func marshalJSON(m *marshaler, msg protoreflect.Message) (string, error) {
+	raw, err := protojson.MarshalOptions{Resolver: m.resolver()}.Marshal(msg.Interface())
+	if err != nil {
+		return "", err
+	}
+	if raw[0] == '"' && msg.Descriptor().FullName() != "google.protobuf.Value" {
+		str := ""
+		err := json.Unmarshal(raw, &str)
+		return str, err
+	}
+	// protojson randomly adds spaces to its output, compacting it makes args stable.
+	compact := bytes.Buffer{}
+	err = json.Compact(&compact, raw)
+	if err != nil {
+		return "", err
+	}
+	return compact.String(), nil
+}
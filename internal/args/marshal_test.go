@@ -0,0 +1,310 @@
+package args
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestMarshal(t *testing.T) {
+	fileDescSet := descriptorpb.FileDescriptorSet{}
+	err := proto.Unmarshal(rawProto, &fileDescSet)
+	require.NoError(t, err)
+	files, err := protodesc.NewFiles(&fileDescSet)
+	require.NoError(t, err)
+	types := newTestTypes(t, files)
+
+	desc, err := files.FindDescriptorByName("test.Simple")
+	require.NoError(t, err)
+	simpleDesc := desc.(protoreflect.MessageDescriptor)
+
+	run := func(options MarshalOptions, body string, expected []string) {
+		message := dynamicpb.NewMessage(simpleDesc)
+		err := protojson.UnmarshalOptions{Resolver: types}.Unmarshal([]byte(body), message)
+		require.NoError(t, err)
+
+		options.Resolver = types
+		args, err := options.Marshal(message)
+		require.NoError(t, err)
+		assert.Equal(t, expected, args)
+
+		// Args must be parsed back to the same message
+		res := dynamicpb.NewMessage(simpleDesc)
+		err = UnmarshalOptions{Resolver: types, Now: testNow}.Unmarshal(args, res)
+		require.NoError(t, err)
+		assert.True(t, proto.Equal(message, res), "expected %v, got %v", message, res)
+	}
+
+	t.Run("Simple", func(t *testing.T) {
+		run(MarshalOptions{}, `{
+			"str": "abc",
+			"int32": -32,
+			"uint64": "64",
+			"double": 6.4,
+			"float": 0.1,
+			"bool": true,
+			"enum": "enum_value2",
+			"nested": {"str": "a", "strs": ["b", "c"]},
+			"bytes": "aGVsbG8=",
+			"strs": ["a", "[b]", "null"],
+			"nesteds": [{}, {"str": "a"}],
+			"labels": {"b": "b", "a": "a", "a.b": "c", "x=y": "z"},
+			"int32_map": {"10": "a", "-1": "b", "2": "c"},
+			"limits": {"cpu": {"max": "4"}, "mem": {}},
+			"kind_str": "abc"
+		}`, []string{
+			"str=abc",
+			"int32=-32",
+			"uint64=64",
+			"double=6.4",
+			"bool=true",
+			"enum=enum_value2",
+			"nested.str=a",
+			"nested.strs.0=b",
+			"nested.strs.1=c",
+			"float=0.1",
+			"bytes=0x68656c6c6f",
+			"strs.0=a",
+			"strs.1=[b]",
+			"strs.2=null",
+			"nesteds.0=",
+			"nesteds.1.str=a",
+			"labels.a=a",
+			"labels.b=b",
+			`labels={"a.b":"c","x=y":"z"}`,
+			"limits.cpu.max=4",
+			"limits.mem=",
+			"int32_map.-1=b",
+			"int32_map.2=c",
+			"int32_map.10=a",
+			"kind_str=abc",
+		})
+	})
+
+	t.Run("Well known types", func(t *testing.T) {
+		run(MarshalOptions{}, `{
+			"wrapper_str": "",
+			"wrapper_int32": 0,
+			"wrapper_bytes": "aGk=",
+			"timestamp": "2024-05-01T10:00:00.500Z",
+			"duration": "-1.5s",
+			"field_mask": "name,nested.strs",
+			"empty": {},
+			"struct": {"b": [1, "a"], "a": null},
+			"value": "null",
+			"list_value": [],
+			"wrapper_strs": ["a", "null"]
+		}`, []string{
+			"wrapper_str=",
+			"wrapper_int32=0",
+			"wrapper_bytes=0x6869",
+			"timestamp=2024-05-01T10:00:00.500Z",
+			"duration=-1.500s",
+			"field_mask=name,nested.strs",
+			"empty={}",
+			`struct={"a":null,"b":[1,"a"]}`,
+			`value="null"`,
+			"list_value=[]",
+			"wrapper_strs.0=a",
+			"wrapper_strs.1=null",
+		})
+	})
+
+	t.Run("Any", func(t *testing.T) {
+		run(MarshalOptions{}, `{"any": {"@type": "type.googleapis.com/test.Simple.Nested", "str": "a"}}`, []string{
+			"any.@type=test.Simple.Nested",
+			"any.str=a",
+		})
+		run(MarshalOptions{}, `{"any": {"@type": "type.googleapis.com/google.protobuf.Duration", "value": "1s"}}`, []string{
+			"any.@type=google.protobuf.Duration",
+			"any.value=1s",
+		})
+	})
+
+	t.Run("Json naming", func(t *testing.T) {
+		run(MarshalOptions{Naming: NamingStyleJSON}, `{"wrapper_str": "a", "int32_map": {"1": "a"}, "kind_nested": {"strs": ["a"]}}`, []string{
+			"wrapperStr=a",
+			"int32Map.1=a",
+			"kindNested.strs.0=a",
+		})
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		message := dynamicpb.NewMessage(simpleDesc)
+		err := protojson.UnmarshalOptions{Resolver: types}.Unmarshal([]byte(`{"str": "a", "wrapper_str": "null"}`), message)
+		require.NoError(t, err)
+		_, err = MarshalOptions{Resolver: types}.Marshal(message)
+		assert.EqualError(t, err, "cannot marshal wrapper_str: a google.protobuf.StringValue set to null cannot be represented as an arg")
+
+		// Any types are resolved using the global registry by default
+		message = dynamicpb.NewMessage(simpleDesc)
+		err = protojson.UnmarshalOptions{Resolver: types}.Unmarshal([]byte(`{"str": "a", "any": {"@type": "type.googleapis.com/test.Simple.Nested"}}`), message)
+		require.NoError(t, err)
+		_, err = MarshalOptions{}.Marshal(message)
+		assert.Error(t, err)
+		assert.Equal(t, []string{"str=a"}, Marshal(message))
+	})
+}
+
+// TestMarshal_roundTrip checks that random messages are unmarshaled back from their args.
+func TestMarshal_roundTrip(t *testing.T) {
+	fileDescSet := descriptorpb.FileDescriptorSet{}
+	err := proto.Unmarshal(rawProto, &fileDescSet)
+	require.NoError(t, err)
+	files, err := protodesc.NewFiles(&fileDescSet)
+	require.NoError(t, err)
+	types := newTestTypes(t, files)
+
+	desc, err := files.FindDescriptorByName("test.Simple")
+	require.NoError(t, err)
+
+	g := &messageGenerator{rand: rand.New(rand.NewSource(1)), types: types}
+	for i := 0; i < 500; i++ {
+		message := dynamicpb.NewMessage(desc.(protoreflect.MessageDescriptor))
+		g.fill(t, message, 0)
+
+		args, err := MarshalOptions{Resolver: types}.Marshal(message)
+		require.NoError(t, err)
+
+		res := dynamicpb.NewMessage(desc.(protoreflect.MessageDescriptor))
+		err = UnmarshalOptions{Resolver: types, Now: testNow}.Unmarshal(args, res)
+		require.NoError(t, err, "args: %q", args)
+		require.True(t, proto.Equal(message, res), "args: %q\nexpected: %v\ngot: %v", args, message, res)
+	}
+}
+
+// Strings that have a meaning in args syntax.
+var generatorStrings = []string{"", "a", "abc", "null", "a.b", "x=y", "[]", "k+", "[a,b]", `{"a":1}`, "-a", "@file", "0x12", "héllo wörld", " "}
+
+var generatorJSONValues = map[protoreflect.FullName][]string{
+	"google.protobuf.Struct":    {`{}`, `{"a":1,"b":[true,null],"c":{"d":"e"}}`},
+	"google.protobuf.ListValue": {`[]`, `[1,"a",{"b":null}]`},
+	"google.protobuf.Value":     {`null`, `"null"`, `1.5`, `"a"`, `[1]`, `{"a":false}`},
+	"google.protobuf.Empty":     {`{}`},
+}
+
+type messageGenerator struct {
+	rand  *rand.Rand
+	types *protoregistry.Types
+}
+
+func (g *messageGenerator) fill(t *testing.T, message protoreflect.Message, depth int) {
+	desc := message.Descriptor()
+	switch desc.FullName() {
+	case "google.protobuf.Timestamp":
+		message.Set(desc.Fields().ByName("seconds"), protoreflect.ValueOfInt64(g.rand.Int63n(4e9)))
+		message.Set(desc.Fields().ByName("nanos"), protoreflect.ValueOfInt32(g.rand.Int31n(1e9)))
+		return
+	case "google.protobuf.Duration":
+		seconds, nanos := g.rand.Int63n(1e6), g.rand.Int31n(1e9)
+		if g.rand.Intn(2) == 0 {
+			seconds, nanos = -seconds, -nanos
+		}
+		message.Set(desc.Fields().ByName("seconds"), protoreflect.ValueOfInt64(seconds))
+		message.Set(desc.Fields().ByName("nanos"), protoreflect.ValueOfInt32(nanos))
+		return
+	case "google.protobuf.FieldMask":
+		paths := message.Mutable(desc.Fields().ByName("paths")).List()
+		for _, path := range []string{"a", "b_c", "d.e_f"}[:g.rand.Intn(4)] {
+			paths.Append(protoreflect.ValueOfString(path))
+		}
+		return
+	case "google.protobuf.Any":
+		nestedType, err := g.types.FindMessageByName("test.Simple.Nested")
+		require.NoError(t, err)
+		nested := nestedType.New()
+		g.fill(t, nested, depth+1)
+		raw, err := proto.MarshalOptions{Deterministic: true}.Marshal(nested.Interface())
+		require.NoError(t, err)
+		message.Set(desc.Fields().ByName("type_url"), protoreflect.ValueOfString(anyTypeURLPrefix+"test.Simple.Nested"))
+		message.Set(desc.Fields().ByName("value"), protoreflect.ValueOfBytes(raw))
+		return
+	}
+	if values, isJSON := generatorJSONValues[desc.FullName()]; isJSON {
+		value := values[g.rand.Intn(len(values))]
+		require.NoError(t, protojson.Unmarshal([]byte(value), message.Interface()))
+		return
+	}
+
+	isWrapper := desc.ParentFile().Path() == "google/protobuf/wrappers.proto"
+	for i := 0; i < desc.Fields().Len(); i++ {
+		field := desc.Fields().Get(i)
+		if !isWrapper && g.rand.Intn(2) == 0 {
+			continue
+		}
+		if oneof := field.ContainingOneof(); oneof != nil && message.WhichOneof(oneof) != nil {
+			continue
+		}
+
+		switch {
+		case field.IsList():
+			list := message.Mutable(field).List()
+			for j := g.rand.Intn(4); j > 0; j-- {
+				list.Append(g.value(t, field, list.NewElement(), depth))
+			}
+		case field.IsMap():
+			m := message.Mutable(field).Map()
+			for j := g.rand.Intn(4); j > 0; j-- {
+				key := g.value(t, field.MapKey(), protoreflect.Value{}, depth).MapKey()
+				m.Set(key, g.value(t, field.MapValue(), m.NewValue(), depth))
+			}
+		case field.Kind() == protoreflect.MessageKind:
+			if depth < 3 {
+				g.fill(t, message.Mutable(field).Message(), depth+1)
+			}
+		default:
+			value := g.value(t, field, protoreflect.Value{}, depth)
+			// A wrapper set to null would be cleared
+			if isWrapper && value.Interface() == nullValue {
+				value = protoreflect.ValueOfString("")
+			}
+			message.Set(field, value)
+		}
+	}
+}
+
+// value returns a random value for a field. newMessage is used for message fields.
+func (g *messageGenerator) value(t *testing.T, field protoreflect.FieldDescriptor, newMessage protoreflect.Value, depth int) protoreflect.Value {
+	r := g.rand
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		return protoreflect.ValueOfBool(r.Intn(2) == 0)
+	case protoreflect.EnumKind:
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(r.Intn(4)))
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return protoreflect.ValueOfInt32(int32(r.Uint32()))
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return protoreflect.ValueOfInt64(int64(r.Uint64()))
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return protoreflect.ValueOfUint32(r.Uint32())
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return protoreflect.ValueOfUint64(r.Uint64())
+	case protoreflect.FloatKind:
+		floats := []float32{0, -1.5, float32(math.Inf(1)), math.MaxFloat32, math.SmallestNonzeroFloat32, r.Float32() * 1000}
+		return protoreflect.ValueOfFloat32(floats[r.Intn(len(floats))])
+	case protoreflect.DoubleKind:
+		doubles := []float64{0, -1.5, math.Inf(-1), math.MaxFloat64, math.SmallestNonzeroFloat64, r.NormFloat64() * 1e10}
+		return protoreflect.ValueOfFloat64(doubles[r.Intn(len(doubles))])
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(generatorStrings[r.Intn(len(generatorStrings))])
+	case protoreflect.BytesKind:
+		b := make([]byte, r.Intn(8))
+		r.Read(b)
+		return protoreflect.ValueOfBytes(b)
+	case protoreflect.MessageKind:
+		g.fill(t, newMessage.Message(), depth+1)
+		return newMessage
+	}
+	t.Fatalf("unsupported kind %s", field.Kind())
+	return protoreflect.Value{}
+}
@@ -0,0 +1,60 @@
+package args
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// NamingStyle is the style used to name args in usage and autocompletion.
+// Args are always accepted in both styles.
+type NamingStyle string
+
+const (
+	// NamingStyleProto uses the field names as defined in the .proto file (e.g. organization_id).
+	NamingStyleProto = NamingStyle("proto")
+	// NamingStyleJSON uses the json names of the fields (e.g. organizationId).
+	NamingStyleJSON = NamingStyle("json")
+)
+
+// ArgName returns the arg name of a field in the given naming style.
+func ArgName(field protoreflect.FieldDescriptor, style NamingStyle) string {
+	if style == NamingStyleJSON {
+		return field.JSONName()
+	}
+	return string(field.Name())
+}
+
+// FindField returns the field matching an arg name word.
+// Proto names and json names are accepted, exact matches take precedence over case-insensitive ones.
+func FindField(desc protoreflect.MessageDescriptor, name string) (protoreflect.FieldDescriptor, error) {
+	fields := desc.Fields()
+	if field := fields.ByName(protoreflect.Name(name)); field != nil {
+		return field, nil
+	}
+	if field := fields.ByJSONName(name); field != nil {
+		return field, nil
+	}
+
+	matches := []protoreflect.FieldDescriptor(nil)
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if strings.EqualFold(string(field.Name()), name) || strings.EqualFold(field.JSONName(), name) {
+			matches = append(matches, field)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("unknown field %s", name)
+	case 1:
+		return matches[0], nil
+	default:
+		names := []protoreflect.Name(nil)
+		for _, field := range matches {
+			names = append(names, field.Name())
+		}
+		return nil, &AmbiguousArgError{ArgName: name, Fields: names}
+	}
+}
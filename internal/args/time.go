@@ -0,0 +1,115 @@
+package args
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dates without time zone are evaluated in the location of the clock.
+var dateLayouts = []string{
+	"2006-01-02",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+}
+
+// Epochs greater than this value are considered to be in milliseconds.
+// In seconds, this would be in year 5138.
+const maxEpochSeconds = 1e11
+
+var daysRegexp = regexp.MustCompile(`^([-+]?)(\d+)d(.*)$`)
+
+func (o UnmarshalOptions) now() time.Time {
+	if o.Now == nil {
+		return time.Now()
+	}
+	return o.Now()
+}
+
+// parseTimestamp parses a timestamp. Supported formats are:
+// - RFC3339: 2024-05-01T10:00:00Z
+// - date with optional time: 2024-05-01, 2024-05-01T10:00:00
+// - unix epoch in seconds or milliseconds: 1714557600, 1714557600000
+// - relative times: now, today, now-1h, today+8h, +30m, -2d
+func parseTimestamp(value string, now time.Time) (time.Time, error) {
+	invalidErr := fmt.Errorf("invalid timestamp %s, expected RFC3339, a date (2006-01-02), a unix epoch or a relative time (now, today, now-1h, +30m)", value)
+
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, value, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	if epoch, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if epoch > maxEpochSeconds || epoch < -maxEpochSeconds {
+			return time.Unix(0, epoch*int64(time.Millisecond)), nil
+		}
+		return time.Unix(epoch, 0), nil
+	}
+
+	// Relative time: a base followed by an optional offset.
+	base, offset := now, value
+	switch {
+	case value == "":
+		return time.Time{}, invalidErr
+	case strings.HasPrefix(value, "now"):
+		offset = strings.TrimPrefix(value, "now")
+	case strings.HasPrefix(value, "today"):
+		year, month, day := now.Date()
+		base = time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+		offset = strings.TrimPrefix(value, "today")
+	}
+
+	if offset == "" {
+		return base, nil
+	}
+	if offset[0] != '+' && offset[0] != '-' {
+		return time.Time{}, invalidErr
+	}
+	duration, err := parseDuration(offset)
+	if err != nil {
+		return time.Time{}, invalidErr
+	}
+	return base.Add(duration), nil
+}
+
+// parseDuration parses a duration such as 1h30m, 1.5s (protojson format) or 2d.
+// Days are always 24 hours long.
+func parseDuration(value string) (time.Duration, error) {
+	invalidErr := fmt.Errorf("invalid duration %s, expected a duration such as 1h30m, 1.5s or 2d", value)
+
+	matches := daysRegexp.FindStringSubmatch(value)
+	if matches == nil {
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return 0, invalidErr
+		}
+		return duration, nil
+	}
+
+	sign, rawDays, rest := matches[1], matches[2], matches[3]
+	days, err := strconv.ParseInt(rawDays, 10, 64)
+	if err != nil {
+		return 0, invalidErr
+	}
+	duration := time.Duration(days) * 24 * time.Hour
+	if rest != "" {
+		if rest[0] == '+' || rest[0] == '-' {
+			return 0, invalidErr
+		}
+		restDuration, err := time.ParseDuration(rest)
+		if err != nil {
+			return 0, invalidErr
+		}
+		duration += restDuration
+	}
+
+	if sign == "-" {
+		duration = -duration
+	}
+	return duration, nil
+}
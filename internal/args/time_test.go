@@ -0,0 +1,77 @@
+package args
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseTimestamp(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	require.NoError(t, err)
+	now := time.Date(2024, 5, 1, 10, 30, 0, 0, paris)
+
+	tests := []struct {
+		value    string
+		expected time.Time
+	}{
+		{"2024-05-01T10:00:00Z", time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)},
+		{"2024-05-01T10:00:00.5+02:00", time.Date(2024, 5, 1, 10, 0, 0, 500000000, paris)},
+		{"2024-05-01", time.Date(2024, 5, 1, 0, 0, 0, 0, paris)},
+		{"2024-05-01T08:15:00", time.Date(2024, 5, 1, 8, 15, 0, 0, paris)},
+		{"2024-05-01 08:15:00", time.Date(2024, 5, 1, 8, 15, 0, 0, paris)},
+		{"1714557600", time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)},
+		{"1714557600500", time.Date(2024, 5, 1, 10, 0, 0, 500000000, time.UTC)},
+		{"now", now},
+		{"now-1h", now.Add(-time.Hour)},
+		{"now+1d", now.Add(24 * time.Hour)},
+		{"today", time.Date(2024, 5, 1, 0, 0, 0, 0, paris)},
+		{"today+8h", time.Date(2024, 5, 1, 8, 0, 0, 0, paris)},
+		{"+30m", now.Add(30 * time.Minute)},
+		{"-2d", now.Add(-48 * time.Hour)},
+	}
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			actual, err := parseTimestamp(test.value, now)
+			require.NoError(t, err)
+			assert.True(t, test.expected.Equal(actual), "expected %s, got %s", test.expected, actual)
+		})
+	}
+
+	for _, value := range []string{"", "tomorrow", "now-", "now1h", "today-abc", "2024-13-01"} {
+		t.Run(value, func(t *testing.T) {
+			_, err := parseTimestamp(value, now)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func Test_parseDuration(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"1.5s", 1500 * time.Millisecond},
+		{"1h30m", 90 * time.Minute},
+		{"-300ms", -300 * time.Millisecond},
+		{"2d", 48 * time.Hour},
+		{"1d12h", 36 * time.Hour},
+		{"-1d1h", -25 * time.Hour},
+	}
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			actual, err := parseDuration(test.value)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+
+	for _, value := range []string{"", "1", "abc", "1d-1h", "d"} {
+		t.Run(value, func(t *testing.T) {
+			_, err := parseDuration(value)
+			assert.Error(t, err)
+		})
+	}
+}
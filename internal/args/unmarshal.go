@@ -1,96 +1,395 @@
 package args
 
 import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
+	"github.com/jerome-quere/grpc-cli/internal/util"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// appendIndex is the list index used to append a new element (e.g. strs.[]=a).
+const appendIndex = "[]"
+
+// UnmarshalOptions is a configurable args unmarshaler.
+type UnmarshalOptions struct {
+	// Resolver is used to resolve google.protobuf.Any types.
+	// If nil, protoregistry.GlobalTypes is used.
+	Resolver interface {
+		protoregistry.MessageTypeResolver
+		protoregistry.ExtensionTypeResolver
+	}
+
+	// Now returns the current time used to evaluate relative timestamps such as now-1h.
+	// If nil, time.Now is used.
+	Now func() time.Time
+
+	state *unmarshalState
+}
+
+// unmarshalState keeps track of what was set by the args being unmarshaled.
+type unmarshalState struct {
+	// oneofFields maps oneofs to the field that was set by args.
+	oneofFields map[oneofKey]protoreflect.Name
+}
+
+type oneofKey struct {
+	message protoreflect.Message
+	oneof   protoreflect.FullName
+}
+
+// Unmarshal parses args using default options.
 func Unmarshal(args []string, dest protoreflect.Message) error {
+	return UnmarshalOptions{}.Unmarshal(args, dest)
+}
+
+// Unmarshal parses args and sets the corresponding fields in dest.
+// Fields already set in dest are overridden by args.
+func (o UnmarshalOptions) Unmarshal(args []string, dest protoreflect.Message) error {
+	o.state = &unmarshalState{
+		oneofFields: map[oneofKey]protoreflect.Name{},
+	}
 
 	// Map arg names to their values.
 	// ["arg1=1", "arg2=2", "arg3"] => [ ["arg1","1"], ["arg2","2"], ["arg3",""] ]
 	argsSlice := SplitRaw(args)
 
-	processedArgNames := make(map[string]bool)
+	// Keep track of arg positions as args are not processed in order.
+	positions := make([]int, len(argsSlice))
+	for i := range positions {
+		positions[i] = i + 1
+	}
+
+	// The type of a google.protobuf.Any must be known before setting its fields so @type args are processed first.
+	sort.Stable(&argsSorter{args: argsSlice, positions: positions})
+
+	// Map canonical arg names to the arg that set them.
+	// Aliases of the same arg (e.g. json_name and proto name) share the same canonical name.
+	processedArgNames := make(map[string]*processedArg)
+	// Inline JSON args are merged with dotted args, they overlap when they set the same field
+	// or when one sets a field inside the other (e.g. labels={"env":"a"} and labels.env=b).
+	overlappingArgs := []overlappingArg(nil)
+	errs := ArgErrors(nil)
 
 	// Loop through all arguments
-	for _, kv := range argsSlice {
+	for i, kv := range argsSlice {
 		argName, argValue := kv[0], kv[1]
 		argNameWords := strings.Split(argName, ".")
 
-		if processedArgNames[argName] {
-			return &DuplicateArgError{
-				ArgName: argName,
+		// -nested.str clears a field
+		clear := isClearArg(argName)
+		if clear {
+			argNameWords = strings.Split(strings.TrimPrefix(argName, clearPrefix), ".")
+		}
+
+		// strs+=a is a shortcut for strs.[]=a
+		if strings.HasSuffix(argName, "+") {
+			argNameWords = strings.Split(strings.TrimSuffix(argName, "+"), ".")
+			argNameWords = append(argNameWords, appendIndex)
+		}
+
+		canonicalArgName := canonicalArgName(dest.Descriptor(), argNameWords)
+		if canonicalArgName != "" {
+			if other, exist := processedArgNames[canonicalArgName]; exist {
+				errs = append(errs, &DuplicateArgError{
+					ArgName:       argName,
+					Position:      positions[i],
+					OtherArgName:  other.name,
+					OtherPosition: other.position,
+				})
+				continue
+			}
+			processedArgNames[canonicalArgName] = &processedArg{name: argName, position: positions[i]}
+
+			// Args only setting a message presence (e.g. nested=) do not overlap.
+			names := inlineArgNames(dest.Descriptor(), argNameWords, argValue)
+			if names == nil && (argValue != "" || clear) {
+				words, _ := canonicalArgWords(dest.Descriptor(), argNameWords)
+				names = [][]string{words}
+			}
+			if other := findOverlappingArg(overlappingArgs, names); other != nil {
+				errs = append(errs, &DuplicateArgError{
+					ArgName:       argName,
+					Position:      positions[i],
+					OtherArgName:  other.name,
+					OtherPosition: other.position,
+				})
+				continue
+			}
+			for _, name := range names {
+				overlappingArgs = append(overlappingArgs, overlappingArg{words: name, arg: processedArgNames[canonicalArgName]})
 			}
 		}
 
-		err := unmarshalMessage(dest, argNameWords, argValue)
+		var err error
+		if clear {
+			err = o.clear(dest, argNameWords)
+		} else {
+			err = o.unmarshalMessage(dest, argNameWords, argValue)
+		}
 		if err != nil {
-			return &UnmarshalArgError{
+			errs = append(errs, &UnmarshalArgError{
 				ArgName:  argName,
 				ArgValue: argValue,
+				Position: positions[i],
 				Err:      err,
-			}
+			})
 		}
 
 	}
 
+	if len(errs) > 0 {
+		sort.SliceStable(errs, func(i, j int) bool {
+			return errorPosition(errs[i]) < errorPosition(errs[j])
+		})
+		return errs
+	}
 	return nil
+}
+
+type processedArg struct {
+	name     string
+	position int
+}
+
+// overlappingArg is a field set by an arg, identified by the words of its canonical name.
+type overlappingArg struct {
+	words []string
+	arg   *processedArg
+}
+
+// inlineArgNames returns the canonical names of the fields set by an inline JSON object (e.g. nested={"str":"a"} sets nested.str).
+// List elements are named by their index in the JSON array. It returns nil if value is not a JSON object.
+func inlineArgNames(desc protoreflect.MessageDescriptor, argNameWords []string, value string) [][]string {
+	if !strings.HasPrefix(value, "{") {
+		return nil
+	}
+	var object interface{}
+	if json.Unmarshal([]byte(value), &object) != nil {
+		return nil
+	}
+
+	names := [][]string{}
+	var walk func(words []string, value interface{})
+	walk = func(words []string, value interface{}) {
+		// Empty objects and lists only set a presence, they do not overlap with other args.
+		switch value := value.(type) {
+		case map[string]interface{}:
+			for key, child := range value {
+				walk(append(append([]string(nil), words...), key), child)
+			}
+		case []interface{}:
+			for i, child := range value {
+				walk(append(append([]string(nil), words...), strconv.Itoa(i)), child)
+			}
+		default:
+			canonicalWords, _ := canonicalArgWords(desc, words)
+			names = append(names, canonicalWords)
+		}
+	}
+	walk(argNameWords, object)
+	return names
+}
 
-	//f := msg.Descriptor().Fields().ByName("organization_id")
-	//
-	//v := protoreflect.ValueOf("test")
-	//
-	//ff := dynamicpb.NewMessage(f.Message())
-	//ffd := ff.Descriptor().Fields().ByName("value")
-	//ff.Set(ffd, v)
-	//
-	//msg.Set(f, protoreflect.ValueOfMessage(ff))
-	//return nil
+// findOverlappingArg returns the arg that set one of the given names, a parent or a child of one of them.
+func findOverlappingArg(args []overlappingArg, names [][]string) *processedArg {
+	for _, name := range names {
+		for _, other := range args {
+			if isWordsPrefix(name, other.words) || isWordsPrefix(other.words, name) {
+				return other.arg
+			}
+		}
+	}
+	return nil
 }
 
-func unmarshalMessage(dest protoreflect.Message, argNameWords []string, value string) error {
+// isWordsPrefix returns true if prefix is equal to or a parent of words.
+func isWordsPrefix(prefix []string, words []string) bool {
+	if len(prefix) > len(words) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != words[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// argsSorter sorts args so that google.protobuf.Any types are set first.
+type argsSorter struct {
+	args      [][2]string
+	positions []int
+}
+
+func (s *argsSorter) Len() int {
+	return len(s.args)
+}
+
+func (s *argsSorter) Less(i, j int) bool {
+	return isAnyTypeArg(s.args[i][0]) && !isAnyTypeArg(s.args[j][0])
+}
+
+func (s *argsSorter) Swap(i, j int) {
+	s.args[i], s.args[j] = s.args[j], s.args[i]
+	s.positions[i], s.positions[j] = s.positions[j], s.positions[i]
+}
+
+// canonicalArgName returns the name of an arg using proto field names and normalized list indexes and map keys.
+// An empty name is returned for args that append to a list as they cannot be duplicated.
+func canonicalArgName(desc protoreflect.MessageDescriptor, argNameWords []string) string {
+	words, appends := canonicalArgWords(desc, argNameWords)
+	if appends {
+		return ""
+	}
+	return strings.Join(words, ".")
+}
+
+// canonicalArgWords returns the words of the canonical name of an arg and whether the arg appends to a list.
+// The words of an appending arg stop at the list field.
+// Parts of the name that cannot be resolved from the descriptor (e.g. google.protobuf.Any fields) are kept as is.
+func canonicalArgWords(desc protoreflect.MessageDescriptor, argNameWords []string) ([]string, bool) {
+	words := []string(nil)
+	for i := 0; i < len(argNameWords); i++ {
+		if desc == nil || desc.FullName() == anyFullName {
+			return append(words, argNameWords[i:]...), false
+		}
+		if _, hasUnmarshalFunc := unmarshalFuncs[desc.FullName()]; hasUnmarshalFunc {
+			return append(words, argNameWords[i:]...), false
+		}
+
+		field, err := FindField(desc, argNameWords[i])
+		if err != nil {
+			return append(words, argNameWords[i:]...), false
+		}
+		words = append(words, string(field.Name()))
+
+		switch {
+		case field.IsMap():
+			if i+1 < len(argNameWords) {
+				i++
+				key, err := unmarshalMapKey(field.MapKey(), argNameWords[i])
+				if err != nil {
+					return append(words, argNameWords[i:]...), false
+				}
+				words = append(words, key.String())
+			}
+			desc = field.MapValue().Message()
+		case field.IsList():
+			// Appending to a list can be done multiple times
+			if i+1 == len(argNameWords) || argNameWords[i+1] == appendIndex {
+				return words, true
+			}
+			i++
+			index, err := strconv.ParseUint(argNameWords[i], 10, 64)
+			if err != nil {
+				return append(words, argNameWords[i:]...), false
+			}
+			words = append(words, strconv.FormatUint(index, 10))
+			desc = field.Message()
+		default:
+			desc = field.Message()
+		}
+	}
+	return words, false
+}
+
+func (o UnmarshalOptions) unmarshalMessage(dest protoreflect.Message, argNameWords []string, value string) error {
 	if unmarshal, hasUnmarshalFunc := unmarshalFuncs[dest.Descriptor().FullName()]; hasUnmarshalFunc {
-		// TODO add if len(argNameWords) > 0
-		return unmarshal(value, dest)
+		if len(argNameWords) > 0 {
+			return fmt.Errorf("cannot set nested field %s", argNameWords[0])
+		}
+		return unmarshal(o, value, dest)
 	}
 
+	// A message can be set using an inline JSON value (e.g. nested={"str":"a"})
+	// An empty value only sets the message presence (e.g. nested=)
 	if len(argNameWords) == 0 {
-		return fmt.Errorf("trying to set a value to a message not a field")
+		if value == "" {
+			return nil
+		}
+		return o.mergeJSON(dest, value)
 	}
 
-	field := dest.Descriptor().Fields().ByName(protoreflect.Name(argNameWords[0]))
-	if field == nil {
-		return fmt.Errorf("unknown field %s", argNameWords[0])
+	if dest.Descriptor().FullName() == anyFullName {
+		return o.unmarshalAny(dest, argNameWords, value)
+	}
+
+	field, err := FindField(dest.Descriptor(), argNameWords[0])
+	if err != nil {
+		return err
 	}
-	return set(field, dest, argNameWords[1:], value)
+	return o.set(field, dest, argNameWords[1:], value)
 }
 
-func set(field protoreflect.FieldDescriptor, dest protoreflect.Message, argNameWords []string, value string) error {
+func (o UnmarshalOptions) set(field protoreflect.FieldDescriptor, dest protoreflect.Message, argNameWords []string, value string) error {
+
+	if len(argNameWords) > 0 && argNameWords[0] == appendIndex && !field.IsList() {
+		return fmt.Errorf("cannot append to %s, it is not a repeated field", field.Name())
+	}
+
+	// Only one field of a oneof can be set by args. Setting the same field multiple times is allowed to set nested fields.
+	// A oneof field that was already set in dest before unmarshaling args is overridden.
+	if oneof := field.ContainingOneof(); oneof != nil && !oneof.IsSynthetic() {
+		key := oneofKey{message: dest, oneof: oneof.FullName()}
+		if other, exist := o.state.oneofFields[key]; exist && other != field.Name() {
+			return &OneofConflictError{
+				Oneof:      oneof.FullName(),
+				Field:      field.Name(),
+				OtherField: other,
+			}
+		}
+		o.state.oneofFields[key] = field.Name()
+	}
+
+	// Same as a JSON null, null clears the field (e.g. wrapper_str=null)
+	if len(argNameWords) == 0 && value == nullValue && isNullable(field) {
+		o.clearField(dest, field)
+		return nil
+	}
 
 	switch {
 	case field.IsList():
 		// If type is a slice:
 
-		// We cannot handle slice without an index notation.
+		// A list can be set using an inline value (e.g. strs=[a,b] or nesteds=[{"str":"a"}])
+		if len(argNameWords) == 0 && strings.HasPrefix(value, "[") {
+			return o.unmarshalInlineList(field, dest, value)
+		}
+
+		// Without index a new element is appended (e.g. strs=a strs=b)
 		if len(argNameWords) == 0 {
-			return fmt.Errorf("missing index on array")
+			argNameWords = []string{appendIndex}
 		}
 
+		list := dest.Mutable(field).List()
+
 		// We check if argNameWords[0] is a positive integer to handle cases like keys.0.value=12
-		index, err := strconv.ParseUint(argNameWords[0], 10, 64)
-		if err != nil {
-			return fmt.Errorf("invalid index array %s", argNameWords[0])
+		// The [] index appends a new element to handle cases like keys.[].value=12
+		index := list.Len()
+		if argNameWords[0] != appendIndex {
+			parsedIndex, err := strconv.ParseUint(argNameWords[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid index array %s", argNameWords[0])
+			}
+			index = int(parsedIndex)
 		}
 
-		list := dest.Mutable(field).List()
 		// Make sure array is big enough to access the correct index.
-		diff := int(index) - list.Len()
+		diff := index - list.Len()
 		switch {
 		case diff > 0:
 			return fmt.Errorf("missing index %d in array", index)
@@ -101,44 +400,61 @@ func set(field protoreflect.FieldDescriptor, dest protoreflect.Message, argNameW
 			// Element already exist at current index.
 		}
 
-		item := list.Get(int(index))
+		item := list.Get(index)
 
 		if field.Kind() == protoreflect.MessageKind {
-			return unmarshalMessage(item.Message(), argNameWords[1:], value)
+			return o.unmarshalMessage(item.Message(), argNameWords[1:], value)
 		}
 
-		err = unmarshalValue(field, &item, argNameWords[1:], value)
+		err := unmarshalValue(field, &item, argNameWords[1:], value)
 		if err != nil {
 			return err
 		}
-		list.Set(int(index), item)
-
-	//case protoreflect.Map:
-	//
-	//	// If map is nil we create it.
-	//	if dest.IsNil() {
-	//		dest.Set(reflect.MakeMap(dest.Type()))
-	//	}
-	//	if len(argNameWords) == 0 {
-	//		return &MissingMapKeyError{}
-	//	}
-	//
-	//	// Create a new value if it does not exist, then call set and add result in the map
-	//	mapKey := reflect.ValueOf(argNameWords[0])
-	//	mapValue := dest.MapIndex(mapKey)
-	//
-	//	if !mapValue.IsValid() {
-	//		mapValue = reflect.New(dest.Type().Elem()).Elem()
-	//	}
-	//	err := set(mapValue, argNameWords[1:], value)
-	//	dest.SetMapIndex(mapKey, mapValue)
-	//
-	//	return err
+		list.Set(index, item)
+
+	case field.IsMap():
+		// If type is a map:
+
+		// A map can be set using an inline JSON value (e.g. labels={"env":"prod"})
+		if len(argNameWords) == 0 && strings.HasPrefix(value, "{") {
+			return o.unmarshalInlineField(field, dest, value)
+		}
+
+		// We cannot handle map without a key notation.
+		if len(argNameWords) == 0 {
+			return fmt.Errorf("missing map key")
+		}
+
+		key, err := unmarshalMapKey(field.MapKey(), argNameWords[0])
+		if err != nil {
+			return err
+		}
+
+		m := dest.Mutable(field).Map()
+		valueField := field.MapValue()
+
+		// null removes the entry (e.g. limits.cpu=null)
+		if len(argNameWords) == 1 && value == nullValue && isNullable(valueField) {
+			m.Clear(key)
+			return nil
+		}
+
+		if valueField.Kind() == protoreflect.MessageKind {
+			// Mutable create the entry if it does not exist yet so multiple args can set fields of the same entry.
+			return o.unmarshalMessage(m.Mutable(key).Message(), argNameWords[1:], value)
+		}
+
+		item := protoreflect.Value{}
+		err = unmarshalValue(valueField, &item, argNameWords[1:], value)
+		if err != nil {
+			return err
+		}
+		m.Set(key, item)
 
 	default:
 
 		if field.Kind() == protoreflect.MessageKind {
-			return unmarshalMessage(dest.Mutable(field).Message(), argNameWords, value)
+			return o.unmarshalMessage(dest.Mutable(field).Message(), argNameWords, value)
 		}
 
 		v := protoreflect.Value{}
@@ -152,14 +468,79 @@ func set(field protoreflect.FieldDescriptor, dest protoreflect.Message, argNameW
 	return nil
 }
 
+// mergeJSON unmarshals an inline JSON value and merges it into dest.
+// Lists are appended and maps are merged so that inline values can be combined with dotted args.
+func (o UnmarshalOptions) mergeJSON(dest protoreflect.Message, value string) error {
+	message := dest.New()
+	err := protojson.UnmarshalOptions{Resolver: o.resolver()}.Unmarshal([]byte(value), message.Interface())
+	if err != nil {
+		return err
+	}
+	proto.Merge(dest.Interface(), message.Interface())
+	return nil
+}
+
+// unmarshalInlineField merges an inline JSON value into a single field of dest.
+func (o UnmarshalOptions) unmarshalInlineField(field protoreflect.FieldDescriptor, dest protoreflect.Message, value string) error {
+	message := dest.New()
+	raw := "{" + strconv.Quote(field.JSONName()) + ":" + value + "}"
+	err := protojson.UnmarshalOptions{Resolver: o.resolver()}.Unmarshal([]byte(raw), message.Interface())
+	if err != nil {
+		return err
+	}
+	proto.Merge(dest.Interface(), message.Interface())
+	return nil
+}
+
+// unmarshalInlineList appends the elements of an inline list to a list field.
+// Lists can either be JSON arrays or use a short form where elements are separated by commas (e.g. strs=[a,b]).
+func (o UnmarshalOptions) unmarshalInlineList(field protoreflect.FieldDescriptor, dest protoreflect.Message, value string) error {
+	if json.Valid([]byte(value)) {
+		return o.unmarshalInlineField(field, dest, value)
+	}
+	if !strings.HasSuffix(value, "]") {
+		return fmt.Errorf("invalid list %s", value)
+	}
+
+	list := dest.Mutable(field).List()
+	content := strings.TrimSpace(value[1 : len(value)-1])
+	if content == "" {
+		return nil
+	}
+	for _, itemValue := range strings.Split(content, ",") {
+		itemValue = strings.TrimSpace(itemValue)
+		item := list.NewElement()
+
+		var err error
+		if field.Kind() == protoreflect.MessageKind {
+			err = o.unmarshalMessage(item.Message(), nil, itemValue)
+		} else {
+			err = unmarshalValue(field, &item, nil, itemValue)
+		}
+		if err != nil {
+			return err
+		}
+		list.Append(item)
+	}
+	return nil
+}
+
 var scalarKinds = map[protoreflect.Kind]bool{
-	protoreflect.Int32Kind:  true,
-	protoreflect.Int64Kind:  true,
-	protoreflect.Uint32Kind: true,
-	protoreflect.Uint64Kind: true,
-	protoreflect.DoubleKind: true,
-	protoreflect.BoolKind:   true,
-	protoreflect.StringKind: true,
+	protoreflect.Int32Kind:    true,
+	protoreflect.Int64Kind:    true,
+	protoreflect.Uint32Kind:   true,
+	protoreflect.Uint64Kind:   true,
+	protoreflect.Sint32Kind:   true,
+	protoreflect.Sint64Kind:   true,
+	protoreflect.Fixed32Kind:  true,
+	protoreflect.Fixed64Kind:  true,
+	protoreflect.Sfixed32Kind: true,
+	protoreflect.Sfixed64Kind: true,
+	protoreflect.FloatKind:    true,
+	protoreflect.DoubleKind:   true,
+	protoreflect.BoolKind:     true,
+	protoreflect.StringKind:   true,
+	protoreflect.BytesKind:    true,
 }
 
 // A type is unmarshalable if:
@@ -192,38 +573,53 @@ func unmarshalValue(field protoreflect.FieldDescriptor, dest *protoreflect.Value
 	}
 
 	if field.Kind() == protoreflect.EnumKind {
-		enumValue := field.Enum().Values().ByName(protoreflect.Name(value))
-		if enumValue == nil {
-			return fmt.Errorf("unknown enum value %s", value)
+		number, err := unmarshalEnum(field.Enum(), value)
+		if err != nil {
+			return err
 		}
-		*dest = protoreflect.ValueOfEnum(enumValue.Number())
+		*dest = protoreflect.ValueOfEnum(number)
 		return nil
 	}
 
 	return unmarshalScalar(field, dest, value)
 }
 
+// unmarshalMapKey parses a map key from its string representation.
+// Map keys can be any integral or string type.
+func unmarshalMapKey(field protoreflect.FieldDescriptor, value string) (protoreflect.MapKey, error) {
+	key := protoreflect.Value{}
+	err := unmarshalScalar(field, &key, value)
+	if err != nil {
+		return protoreflect.MapKey{}, fmt.Errorf("invalid map key %s: %w", value, err)
+	}
+	return key.MapKey(), nil
+}
+
 // unmarshalScalar handles unmarshaling from a string to a scalar type .
 // It handles transformation like Atoi if dest is an Integer.
 func unmarshalScalar(field protoreflect.FieldDescriptor, dest *protoreflect.Value, value string) error {
 
 	switch field.Kind() {
-	case protoreflect.Int32Kind:
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
 		i, err := strconv.ParseInt(value, 0, 32)
 		*dest = protoreflect.ValueOfInt32(int32(i))
 		return err
-	case protoreflect.Int64Kind:
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
 		i, err := strconv.ParseInt(value, 0, 64)
 		*dest = protoreflect.ValueOfInt64(int64(i))
 		return err
-	case protoreflect.Uint32Kind:
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
 		i, err := strconv.ParseUint(value, 0, 32)
 		*dest = protoreflect.ValueOfUint32(uint32(i))
 		return err
-	case protoreflect.Uint64Kind:
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
 		i, err := strconv.ParseUint(value, 0, 64)
 		*dest = protoreflect.ValueOfUint64(i)
 		return err
+	case protoreflect.FloatKind:
+		f, err := strconv.ParseFloat(value, 32)
+		*dest = protoreflect.ValueOfFloat32(float32(f))
+		return err
 	case protoreflect.DoubleKind:
 		f, err := strconv.ParseFloat(value, 64)
 		*dest = protoreflect.ValueOfFloat64(f)
@@ -241,21 +637,65 @@ func unmarshalScalar(field protoreflect.FieldDescriptor, dest *protoreflect.Valu
 	case protoreflect.StringKind:
 		*dest = protoreflect.ValueOfString(value)
 		return nil
+	case protoreflect.BytesKind:
+		b, err := unmarshalBytes(value)
+		*dest = protoreflect.ValueOfBytes(b)
+		return err
 	default:
 		return fmt.Errorf("don't know how to unmarshal %s", field.Kind())
 	}
 }
 
-type UnmarshalFunc func(value string, message protoreflect.Message) error
+// unmarshalBytes decodes a bytes value. Supported formats are:
+// - @path: content of the file at path
+// - 0x...: hex encoded bytes
+// - base64 encoded bytes (standard or URL-safe, padding is optional)
+func unmarshalBytes(value string) ([]byte, error) {
+	switch {
+	case strings.HasPrefix(value, "@"):
+		b, err := ioutil.ReadFile(util.ResolvePath(value[1:]))
+		if err != nil {
+			return nil, fmt.Errorf("cannot read file %s: %w", value[1:], err)
+		}
+		return b, nil
+	case strings.HasPrefix(value, "0x"):
+		b, err := hex.DecodeString(value[2:])
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a valid hex value: %w", value, err)
+		}
+		return b, nil
+	default:
+		// Same as protojson: accept both standard and URL-safe encoding with or without padding.
+		encoding := base64.StdEncoding
+		if strings.ContainsAny(value, "-_") {
+			encoding = base64.URLEncoding
+		}
+		if len(value)%4 != 0 {
+			encoding = encoding.WithPadding(base64.NoPadding)
+		}
+		b, err := encoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a valid base64 value: %w", value, err)
+		}
+		return b, nil
+	}
+}
+
+// UnmarshalFunc sets a message from a single arg value.
+type UnmarshalFunc func(o UnmarshalOptions, value string, message protoreflect.Message) error
 
 var unmarshalFuncs = map[protoreflect.FullName]UnmarshalFunc{
-	"google.protobuf.StringValue": func(value string, dest protoreflect.Message) error {
-		f := dest.Descriptor().Fields().ByName("value")
-		dest.Set(f, protoreflect.ValueOfString(value))
-		return nil
-	},
-	"google.protobuf.Timestamp": func(value string, dest protoreflect.Message) error {
-		date, err := time.Parse(time.RFC3339, value)
+	"google.protobuf.DoubleValue": unmarshalWrapper,
+	"google.protobuf.FloatValue":  unmarshalWrapper,
+	"google.protobuf.Int64Value":  unmarshalWrapper,
+	"google.protobuf.UInt64Value": unmarshalWrapper,
+	"google.protobuf.Int32Value":  unmarshalWrapper,
+	"google.protobuf.UInt32Value": unmarshalWrapper,
+	"google.protobuf.BoolValue":   unmarshalWrapper,
+	"google.protobuf.StringValue": unmarshalWrapper,
+	"google.protobuf.BytesValue":  unmarshalWrapper,
+	"google.protobuf.Timestamp": func(o UnmarshalOptions, value string, dest protoreflect.Message) error {
+		date, err := parseTimestamp(value, o.now())
 		if err != nil {
 			return err
 		}
@@ -267,40 +707,109 @@ var unmarshalFuncs = map[protoreflect.FullName]UnmarshalFunc{
 		dest.Set(nanos, protoreflect.ValueOfInt32(t.Nanos))
 		return nil
 	},
-	"google.protobuf.Int32Value": func(value string, dest protoreflect.Message) error {
-		f := dest.Descriptor().Fields().ByName("value")
-		v, err := strconv.ParseInt(value, 10, 32)
+	"google.protobuf.Duration": func(o UnmarshalOptions, value string, dest protoreflect.Message) error {
+		duration, err := parseDuration(value)
 		if err != nil {
 			return err
 		}
-		dest.Set(f, protoreflect.ValueOfInt32(int32(v)))
+		d := durationpb.New(duration)
+
+		seconds := dest.Descriptor().Fields().ByName("seconds")
+		nanos := dest.Descriptor().Fields().ByName("nanos")
+		dest.Set(seconds, protoreflect.ValueOfInt64(d.Seconds))
+		dest.Set(nanos, protoreflect.ValueOfInt32(d.Nanos))
 		return nil
 	},
-	"google.protobuf.UInt32Value": func(value string, dest protoreflect.Message) error {
-		f := dest.Descriptor().Fields().ByName("value")
-		v, err := strconv.ParseUint(value, 10, 32)
-		if err != nil {
-			return err
+	"google.protobuf.FieldMask": func(o UnmarshalOptions, value string, dest protoreflect.Message) error {
+		paths := dest.Mutable(dest.Descriptor().Fields().ByName("paths")).List()
+		for _, path := range strings.Split(value, ",") {
+			path = strings.TrimSpace(path)
+			if path == "" {
+				continue
+			}
+			// protojson only accepts lowerCamelCase paths, we also accept the proto snake_case names.
+			if !strings.Contains(path, "_") {
+				path = camelToSnakeCase(path)
+			}
+			if !protoreflect.FullName(path).IsValid() {
+				return fmt.Errorf("invalid field mask path %s", path)
+			}
+			paths.Append(protoreflect.ValueOfString(path))
 		}
-		dest.Set(f, protoreflect.ValueOfUint32(uint32(v)))
 		return nil
 	},
-	"google.protobuf.Int64Value": func(value string, dest protoreflect.Message) error {
-		f := dest.Descriptor().Fields().ByName("value")
-		v, err := strconv.ParseInt(value, 10, 64)
-		if err != nil {
-			return err
+	"google.protobuf.Empty": func(o UnmarshalOptions, value string, dest protoreflect.Message) error {
+		if value != "" && value != "{}" {
+			return fmt.Errorf("google.protobuf.Empty only accepts an empty value or {}")
 		}
-		dest.Set(f, protoreflect.ValueOfInt64(v))
 		return nil
 	},
-	"google.protobuf.UInt64Value": func(value string, dest protoreflect.Message) error {
-		f := dest.Descriptor().Fields().ByName("value")
-		v, err := strconv.ParseUint(value, 10, 64)
-		if err != nil {
-			return err
+	"google.protobuf.Struct": unmarshalJSON,
+	"google.protobuf.Value": func(o UnmarshalOptions, value string, dest protoreflect.Message) error {
+		// Value that are not valid JSON are considered as string so that name=foo does not require quotes.
+		if !json.Valid([]byte(value)) {
+			value = strconv.Quote(value)
 		}
-		dest.Set(f, protoreflect.ValueOfUint64(v))
-		return nil
+		return unmarshalJSON(o, value, dest)
 	},
+	"google.protobuf.ListValue": unmarshalJSON,
+}
+
+// unmarshalWrapper sets the value field of a wrapper message such as google.protobuf.StringValue.
+func unmarshalWrapper(_ UnmarshalOptions, value string, dest protoreflect.Message) error {
+	field := dest.Descriptor().Fields().ByName("value")
+	v := protoreflect.Value{}
+	var err error
+
+	// Same as protojson, wrapper integers are decimal (e.g. 010 is 10) where scalar fields also accept 0x, 0o and 0b prefixes.
+	switch field.Kind() {
+	case protoreflect.Int32Kind:
+		var i int64
+		i, err = strconv.ParseInt(value, 10, 32)
+		v = protoreflect.ValueOfInt32(int32(i))
+	case protoreflect.Int64Kind:
+		var i int64
+		i, err = strconv.ParseInt(value, 10, 64)
+		v = protoreflect.ValueOfInt64(i)
+	case protoreflect.Uint32Kind:
+		var i uint64
+		i, err = strconv.ParseUint(value, 10, 32)
+		v = protoreflect.ValueOfUint32(uint32(i))
+	case protoreflect.Uint64Kind:
+		var i uint64
+		i, err = strconv.ParseUint(value, 10, 64)
+		v = protoreflect.ValueOfUint64(i)
+	default:
+		err = unmarshalScalar(field, &v, value)
+	}
+	if err != nil {
+		return err
+	}
+	dest.Set(field, v)
+	return nil
+}
+
+// unmarshalJSON unmarshals a message using its protojson representation.
+func unmarshalJSON(o UnmarshalOptions, value string, dest protoreflect.Message) error {
+	return protojson.UnmarshalOptions{Resolver: o.resolver()}.Unmarshal([]byte(value), dest.Interface())
+}
+
+// camelToSnakeCase converts a lowerCamelCase field mask path to its snake_case form.
+// Same as protojson: "fooBar.baz" => "foo_bar.baz".
+func camelToSnakeCase(path string) string {
+	var b strings.Builder
+	for _, c := range path {
+		if unicode.IsUpper(c) {
+			b.WriteByte('_')
+			c = unicode.ToLower(c)
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// IsScalar returns true if a field value (or list element) can be set using a single arg.
+// This is the case for scalar kinds, enums and well-known types like google.protobuf.Timestamp.
+func IsScalar(field protoreflect.FieldDescriptor) bool {
+	return isUnmarshalableValue(field)
 }
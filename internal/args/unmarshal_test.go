@@ -3,14 +3,19 @@ package args
 import (
 	"bytes"
 	_ "embed"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/protobuf/encoding/protojson"
 
 	"google.golang.org/protobuf/reflect/protodesc"
 	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
 	"google.golang.org/protobuf/types/dynamicpb"
 
 	"github.com/stretchr/testify/require"
@@ -27,18 +32,25 @@ func TestUnmarshal(t *testing.T) {
 	require.NoError(t, err)
 	files, err := protodesc.NewFiles(&fileDescSet)
 	require.NoError(t, err)
+	types := newTestTypes(t, files)
 
-	run := func(args []string, expected string) {
+	// runWithBody unmarshals args on top of a message initialized from a JSON body.
+	runWithBody := func(body string, args []string, expected string) {
 		desc, err := files.FindDescriptorByName("test.Simple")
 		require.NoError(t, err)
 		message := dynamicpb.NewMessage(desc.(protoreflect.MessageDescriptor))
-		err = Unmarshal(args, message)
+		if body != "" {
+			err = protojson.UnmarshalOptions{Resolver: types}.Unmarshal([]byte(body), message)
+			require.NoError(t, err)
+		}
+		err = UnmarshalOptions{Resolver: types, Now: testNow}.Unmarshal(args, message)
 		require.NoError(t, err)
 
 		res, err := protojson.MarshalOptions{
 			UseProtoNames: true,
 			Indent:        "\t",
 			Multiline:     true,
+			Resolver:      types,
 		}.Marshal(message)
 		require.NoError(t, err)
 
@@ -49,6 +61,21 @@ func TestUnmarshal(t *testing.T) {
 		assert.Equal(t, formatExpected(expected), string(res))
 	}
 
+	run := func(args []string, expected string) {
+		runWithBody("", args, expected)
+	}
+
+	runErr := func(args []string, expected string) {
+		desc, err := files.FindDescriptorByName("test.Simple")
+		require.NoError(t, err)
+		message := dynamicpb.NewMessage(desc.(protoreflect.MessageDescriptor))
+		err = UnmarshalOptions{Resolver: types, Now: testNow}.Unmarshal(args, message)
+		require.Error(t, err)
+
+		// protojson randomly use a non-breaking space in its error messages
+		assert.Equal(t, expected, strings.ReplaceAll(err.Error(), "proto:\u00a0", "proto: "))
+	}
+
 	t.Run("Simple", func(t *testing.T) {
 		run([]string{
 			"str=abc",
@@ -138,6 +165,697 @@ func TestUnmarshal(t *testing.T) {
 		`)
 	})
 
+	t.Run("Scalars", func(t *testing.T) {
+		run([]string{
+			"float=3.5",
+			"sint32=-32",
+			"sint64=-64",
+			"fixed32=32",
+			"fixed64=64",
+			"sfixed32=-32",
+			"sfixed64=-64",
+			"bytes=aGVsbG8=",
+		}, `
+			{
+				"float": 3.5,
+				"sint32": -32,
+				"sint64": "-64",
+				"fixed32": 32,
+				"fixed64": "64",
+				"sfixed32": -32,
+				"sfixed64": "-64",
+				"bytes": "aGVsbG8="
+			}
+		`)
+	})
+
+	t.Run("Wrapper integers", func(t *testing.T) {
+		run([]string{
+			"wrapper_int32=010",
+			"wrapper_uint32=08",
+			"wrapper_int64=-007",
+			"wrapper_uint64=0010",
+		}, `
+			{
+				"wrapper_int32": 10,
+				"wrapper_uint32": 8,
+				"wrapper_int64": "-7",
+				"wrapper_uint64": "10"
+			}
+		`)
+		runErr([]string{"wrapper_int32=0x10"}, `unmarshal error for arg #1 wrapper_int32 with value 0x10: strconv.ParseInt: parsing "0x10": invalid syntax`)
+		runErr([]string{"wrapper_uint64=0b11"}, `unmarshal error for arg #1 wrapper_uint64 with value 0b11: strconv.ParseUint: parsing "0b11": invalid syntax`)
+	})
+
+	t.Run("Bytes", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "bytes")
+		require.NoError(t, ioutil.WriteFile(path, []byte("hello"), 0600))
+
+		run([]string{"bytes=aGVsbG8"}, `
+			{
+				"bytes": "aGVsbG8="
+			}
+		`)
+		run([]string{"bytes=_-8"}, `
+			{
+				"bytes": "/+8="
+			}
+		`)
+		run([]string{"bytes=0x68656c6c6f"}, `
+			{
+				"bytes": "aGVsbG8="
+			}
+		`)
+		run([]string{"bytes=@" + path}, `
+			{
+				"bytes": "aGVsbG8="
+			}
+		`)
+
+		runErr([]string{"bytes=0xzz"}, `unmarshal error for arg #1 bytes with value 0xzz: 0xzz is not a valid hex value: encoding/hex: invalid byte: U+007A 'z'`)
+		runErr([]string{"bytes=a!"}, `unmarshal error for arg #1 bytes with value a!: a! is not a valid base64 value: illegal base64 data at input byte 1`)
+	})
+
+	t.Run("Well known types", func(t *testing.T) {
+		run([]string{
+			"wrapper_bool=true",
+			"wrapper_double=6.4",
+			"wrapper_float=3.5",
+			"wrapper_bytes=0x68656c6c6f",
+			"timestamp=2021-05-01T10:00:00Z",
+			"duration=1.5s",
+			"field_mask=name,labels,updateTime,nested.display_name",
+			"empty=",
+			`struct={"a": 1, "b": ["c"]}`,
+			"value=foo",
+			"list_value=[1, true]",
+		}, `
+			{
+				"wrapper_bool": true,
+				"wrapper_double": 6.4,
+				"wrapper_float": 3.5,
+				"wrapper_bytes": "aGVsbG8=",
+				"timestamp": "2021-05-01T10:00:00Z",
+				"duration": "1.500s",
+				"field_mask": "name,labels,updateTime,nested.displayName",
+				"empty": {},
+				"struct": {
+					"a": 1,
+					"b": [
+						"c"
+					]
+				},
+				"value": "foo",
+				"list_value": [
+					1,
+					true
+				]
+			}
+		`)
+
+		run([]string{`value={"a": null}`}, `
+			{
+				"value": {
+					"a": null
+				}
+			}
+		`)
+
+		run([]string{"value=12"}, `
+			{
+				"value": 12
+			}
+		`)
+
+		runErr([]string{"duration=1"}, "unmarshal error for arg #1 duration with value 1: invalid duration 1, expected a duration such as 1h30m, 1.5s or 2d")
+		runErr([]string{"empty=a"}, "unmarshal error for arg #1 empty with value a: google.protobuf.Empty only accepts an empty value or {}")
+		runErr([]string{"field_mask=a-b"}, "unmarshal error for arg #1 field_mask with value a-b: invalid field mask path a-b")
+		runErr([]string{"struct=[]"}, `unmarshal error for arg #1 struct with value []: proto: syntax error (line 1:1): unexpected token [`)
+		runErr([]string{"timestamp.seconds=1"}, "unmarshal error for arg #1 timestamp.seconds with value 1: cannot set nested field seconds")
+	})
+
+	t.Run("Time", func(t *testing.T) {
+		run([]string{
+			"timestamp=now-1h",
+			"duration=1h30m",
+		}, `
+			{
+				"timestamp": "2024-05-01T09:30:00Z",
+				"duration": "5400s"
+			}
+		`)
+
+		runErr([]string{"timestamp=yesterday"}, "unmarshal error for arg #1 timestamp with value yesterday: invalid timestamp yesterday, expected RFC3339, a date (2006-01-02), a unix epoch or a relative time (now, today, now-1h, +30m)")
+	})
+
+	t.Run("Any", func(t *testing.T) {
+		run([]string{
+			"any.str=abc",
+			"any.@type=test.Simple",
+			"any.nested.strs.0=nested",
+			"any.any.@type=google.protobuf.Duration",
+			"any.any.value=1s",
+		}, `
+			{
+				"any": {
+					"@type": "type.googleapis.com/test.Simple",
+					"str": "abc",
+					"nested": {
+						"strs": [
+							"nested"
+						]
+					},
+					"any": {
+						"@type": "type.googleapis.com/google.protobuf.Duration",
+						"value": "1s"
+					}
+				}
+			}
+		`)
+
+		runErr([]string{"any.str=abc"}, "unmarshal error for arg #1 any.str with value abc: type of google.protobuf.Any must be set using @type")
+		runErr([]string{"any.@type=test.Unknown"}, `unmarshal error for arg #1 any.@type with value test.Unknown: cannot resolve type test.Unknown: proto: not found`)
+		runErr([]string{"any.@type=test.Simple", "any.unknown=abc"}, "unmarshal error for arg #2 any.unknown with value abc: unknown field unknown")
+	})
+
+	t.Run("Oneof", func(t *testing.T) {
+		run([]string{
+			"kind_nested.str=abc",
+			"kind_nested.strs.0=abc",
+		}, `
+			{
+				"kind_nested": {
+					"str": "abc",
+					"strs": [
+						"abc"
+					]
+				}
+			}
+		`)
+
+		runErr([]string{"kind_str=abc", "kind_int32=1"}, "unmarshal error for arg #2 kind_int32 with value 1: cannot set kind_int32 as kind_str is already set, they are both part of oneof test.Simple.kind")
+
+		desc, err := files.FindDescriptorByName("test.Simple")
+		require.NoError(t, err)
+		err = Unmarshal([]string{"kind_str=abc", "kind_nested.str=abc"}, dynamicpb.NewMessage(desc.(protoreflect.MessageDescriptor)))
+		// Args override oneof fields that were set before
+		message := dynamicpb.NewMessage(desc.(protoreflect.MessageDescriptor))
+		require.NoError(t, Unmarshal([]string{"kind_str=abc"}, message))
+		require.NoError(t, Unmarshal([]string{"kind_int32=1"}, message))
+		assert.Equal(t, "kind_int32", string(message.WhichOneof(message.Descriptor().Oneofs().ByName("kind")).Name()))
+
+		oneofErr := &OneofConflictError{}
+		require.True(t, errors.As(err, &oneofErr))
+		assert.Equal(t, &OneofConflictError{Oneof: "test.Simple.kind", Field: "kind_nested", OtherField: "kind_str"}, oneofErr)
+	})
+
+	t.Run("Json name", func(t *testing.T) {
+		run([]string{
+			"wrapperStr=abc",
+			"kindNested.strs.0=abc",
+		}, `
+			{
+				"wrapper_str": "abc",
+				"kind_nested": {
+					"strs": [
+						"abc"
+					]
+				}
+			}
+		`)
+	})
+
+	t.Run("Enum", func(t *testing.T) {
+		run([]string{
+			"enum=1",
+			"enums=enum_value1",
+			"enums=ENUM_VALUE2",
+			"enums=value2",
+			"enums=Value1",
+			"enums=5",
+			"enum_map.a=VALUE2",
+		}, `
+			{
+				"enum": "enum_value2",
+				"enums": [
+					"enum_value1",
+					"enum_value2",
+					"enum_value2",
+					"enum_value1",
+					5
+				],
+				"enum_map": {
+					"a": "enum_value2"
+				}
+			}
+		`)
+		runErr([]string{"enum=value3"}, "unmarshal error for arg #1 enum with value value3: unknown enum value value3, expected one of value1, value2")
+		runErr([]string{"enum=enum_"}, "unmarshal error for arg #1 enum with value enum_: unknown enum value enum_, expected one of value1, value2")
+	})
+
+	t.Run("Null", func(t *testing.T) {
+		body := `{
+			"int32": 1,
+			"str": "a",
+			"nested": {"str": "a", "strs": ["a"]},
+			"wrapper_str": "a",
+			"wrapper_int32": 1,
+			"strs": ["a"],
+			"labels": {"a": "a", "b": "b"},
+			"limits": {"cpu": {"max": "4"}, "mem": {"max": "8"}},
+			"kind_nested": {"str": "a"}
+		}`
+		runWithBody(body, []string{
+			"int32=null",
+			"nested.str=null",
+			"wrapper_str=null",
+			"wrapper_int32=null",
+			"strs=null",
+			"labels.a=null",
+			"limits.cpu=null",
+			"kind_nested=null",
+			"kind_int32=2",
+		}, `
+			{
+				"str": "a",
+				"nested": {
+					"str": "null",
+					"strs": [
+						"a"
+					]
+				},
+				"labels": {
+					"a": "null",
+					"b": "b"
+				},
+				"limits": {
+					"mem": {
+						"max": "8"
+					}
+				},
+				"kind_int32": 2
+			}
+		`)
+		run([]string{
+			"strs=null",
+			"strs=a",
+			"value=null",
+			"wrapper_str=null",
+			"limits.cpu.max=null",
+		}, `
+			{
+				"value": null,
+				"strs": [
+					"a"
+				],
+				"limits": {
+					"cpu": {}
+				}
+			}
+		`)
+	})
+
+	t.Run("Clear", func(t *testing.T) {
+		body := `{
+			"str": "a",
+			"nested": {"str": "a", "strs": ["a"]},
+			"wrapper_str": "a",
+			"strs": ["a"],
+			"labels": {"a": "a", "b": "b"},
+			"limits": {"cpu": {"min": "1", "max": "4"}},
+			"nesteds": [{"str": "a", "strs": ["a"]}],
+			"kind_str": "a"
+		}`
+		runWithBody(body, []string{
+			"-str",
+			"-nested.strs",
+			"-wrapperStr",
+			"-strs",
+			"-labels.a",
+			"-limits.cpu.max",
+			"-nesteds.0.str",
+			"-kind_str",
+			"-kind_nested.str",
+			"-struct",
+			"kind_int32=2",
+		}, `
+			{
+				"nested": {
+					"str": "a"
+				},
+				"nesteds": [
+					{
+						"strs": [
+							"a"
+						]
+					}
+				],
+				"labels": {
+					"b": "b"
+				},
+				"limits": {
+					"cpu": {
+						"min": "1"
+					}
+				},
+				"kind_int32": 2
+			}
+		`)
+		runErr([]string{"-unknown"}, "unmarshal error for arg #1 -unknown with value : unknown field unknown")
+		runErr([]string{"-nested.unknown"}, "unmarshal error for arg #1 -nested.unknown with value : unknown field unknown")
+		runErr([]string{"-strs.0"}, "unmarshal error for arg #1 -strs.0 with value : cannot clear element 0 of strs, only whole lists can be cleared")
+		runErr([]string{"-str.a"}, "unmarshal error for arg #1 -str.a with value : cannot clear nested field a")
+		runErr([]string{"-wrapper_str.value"}, "unmarshal error for arg #1 -wrapper_str.value with value : cannot clear nested field value of google.protobuf.StringValue")
+		runErr([]string{"str=a", "-str"}, "duplicate arg #2 -str, already set by arg #1 str")
+	})
+
+	t.Run("Presence", func(t *testing.T) {
+		run([]string{
+			"nested=",
+			"wrapper_str=",
+			"empty=",
+			"limits.cpu=",
+		}, `
+			{
+				"nested": {},
+				"wrapper_str": "",
+				"empty": {},
+				"limits": {
+					"cpu": {}
+				}
+			}
+		`)
+	})
+
+	t.Run("Case insensitive name", func(t *testing.T) {
+		run([]string{
+			"WRAPPER_STR=abc",
+			"kindnested.Strs.0=abc",
+		}, `
+			{
+				"wrapper_str": "abc",
+				"kind_nested": {
+					"strs": [
+						"abc"
+					]
+				}
+			}
+		`)
+		runErr([]string{"STR=a", "str=b"}, "duplicate arg #2 str, already set by arg #1 STR")
+	})
+
+	t.Run("Ambiguous name", func(t *testing.T) {
+		file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+			Name:    proto.String("ambiguous.proto"),
+			Package: proto.String("test"),
+			MessageType: []*descriptorpb.DescriptorProto{{
+				Name: proto.String("Ambiguous"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{Name: proto.String("value"), Number: proto.Int32(1), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()},
+					{Name: proto.String("Value"), Number: proto.Int32(2), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()},
+				},
+			}},
+		}, nil)
+		require.NoError(t, err)
+		message := dynamicpb.NewMessage(file.Messages().Get(0))
+
+		require.NoError(t, Unmarshal([]string{"value=a", "Value=b"}, message))
+		err = Unmarshal([]string{"VALUE=a"}, message)
+		assert.Equal(t, "unmarshal error for arg #1 VALUE with value a: ambiguous arg VALUE, it matches fields value, Value", err.Error())
+		ambiguousErr := &AmbiguousArgError{}
+		assert.True(t, errors.As(err, &ambiguousErr))
+	})
+
+	t.Run("Duplicate", func(t *testing.T) {
+		runErr([]string{"str=a", "str=b"}, "duplicate arg #2 str, already set by arg #1 str")
+		runErr([]string{"wrapper_str=a", "wrapperStr=b"}, "duplicate arg #2 wrapperStr, already set by arg #1 wrapper_str")
+		runErr([]string{"strs.0=a", "strs.00=b"}, "duplicate arg #2 strs.00, already set by arg #1 strs.0")
+		runErr([]string{"int32_map.1=a", "int32Map.01=b"}, "duplicate arg #2 int32Map.01, already set by arg #1 int32_map.1")
+		runErr([]string{"any.@type=test.Simple", "any.str=a", "any.str=b"}, "duplicate arg #3 any.str, already set by arg #2 any.str")
+	})
+
+	t.Run("Duplicate inline", func(t *testing.T) {
+		runErr([]string{"nested.str=b", `nested={"str":"a"}`}, "duplicate arg #2 nested, already set by arg #1 nested.str")
+		runErr([]string{`nested={"str":"a"}`, "nested.str=b"}, "duplicate arg #2 nested.str, already set by arg #1 nested")
+		runErr([]string{"labels.env=a", `labels={"env":"b"}`}, "duplicate arg #2 labels, already set by arg #1 labels.env")
+		runErr([]string{`labels={"env":"b"}`, "labels.env=a"}, "duplicate arg #2 labels.env, already set by arg #1 labels")
+		runErr([]string{`nested={"strs":["a"]}`, "nested.strs.0=b"}, "duplicate arg #2 nested.strs.0, already set by arg #1 nested")
+		runErr([]string{`nested={"str":"a"}`, "-nested.str"}, "duplicate arg #2 -nested.str, already set by arg #1 nested")
+
+		run([]string{"nested.str=a", `nested={"strs":["b"]}`, `labels={"a.b":"c"}`, "labels.a=d"}, `
+			{
+				"nested": {
+					"str": "a",
+					"strs": [
+						"b"
+					]
+				},
+				"labels": {
+					"a": "d",
+					"a.b": "c"
+				}
+			}
+		`)
+	})
+
+	t.Run("Multiple errors", func(t *testing.T) {
+		runErr([]string{"unknown=a", "str=a", "int32=abc", "any.str=a", "str=b"}, `4 invalid args:
+  unmarshal error for arg #1 unknown with value a: unknown field unknown
+  unmarshal error for arg #3 int32 with value abc: strconv.ParseInt: parsing "abc": invalid syntax
+  unmarshal error for arg #4 any.str with value a: type of google.protobuf.Any must be set using @type
+  duplicate arg #5 str, already set by arg #2 str`)
+	})
+
+	t.Run("Inline", func(t *testing.T) {
+		run([]string{
+			`nested={"str": "a", "strs": ["x"]}`,
+			"nested.strs.1=y",
+			"strs=[a, b]",
+			"strs.2=c",
+			"enums=[enum_value2]",
+			`nesteds=[{"str": "a"}]`,
+			"nesteds.1.str=b",
+			"wrapper_strs=[a,b]",
+			`labels={"env": "prod"}`,
+			"labels.team=core",
+			`limits.cpu={"max": 4}`,
+			`any={"@type": "type.googleapis.com/test.Simple.Nested", "str": "a"}`,
+		}, `
+			{
+				"nested": {
+					"str": "a",
+					"strs": [
+						"x",
+						"y"
+					]
+				},
+				"any": {
+					"@type": "type.googleapis.com/test.Simple.Nested",
+					"str": "a"
+				},
+				"strs": [
+					"a",
+					"b",
+					"c"
+				],
+				"enums": [
+					"enum_value2"
+				],
+				"nesteds": [
+					{
+						"str": "a"
+					},
+					{
+						"str": "b"
+					}
+				],
+				"wrapper_strs": [
+					"a",
+					"b"
+				],
+				"labels": {
+					"env": "prod",
+					"team": "core"
+				},
+				"limits": {
+					"cpu": {
+						"max": "4"
+					}
+				}
+			}
+		`)
+
+		run([]string{
+			`strs=["a,b", "c"]`,
+			"nested.str=a",
+			`nested={"strs": ["x"]}`,
+		}, `
+			{
+				"nested": {
+					"str": "a",
+					"strs": [
+						"x"
+					]
+				},
+				"strs": [
+					"a,b",
+					"c"
+				]
+			}
+		`)
+
+		runErr([]string{"nested={"}, "unmarshal error for arg #1 nested with value {: proto: unexpected EOF")
+		runErr([]string{"strs=[a"}, "unmarshal error for arg #1 strs with value [a: invalid list [a")
+		runErr([]string{"enums=[unknown]"}, "unmarshal error for arg #1 enums with value [unknown]: unknown enum value unknown, expected one of value1, value2")
+		runErr([]string{"labels={\"env\": 1}"}, `unmarshal error for arg #1 labels with value {"env": 1}: proto: (line 1:19): invalid value for string type: 1`)
+	})
+
+	t.Run("Append", func(t *testing.T) {
+		run([]string{
+			"strs=a",
+			"strs=b",
+			"strs+=c",
+			"strs.[]=d",
+			"strs.1=B",
+			"nesteds.[].str=a",
+			"nesteds.[].str=b",
+			"nesteds.1.strs+=c",
+			"nesteds.1.strs+=d",
+			"enums+=enum_value2",
+		}, `
+			{
+				"strs": [
+					"a",
+					"B",
+					"c",
+					"d"
+				],
+				"enums": [
+					"enum_value2"
+				],
+				"nesteds": [
+					{
+						"str": "a"
+					},
+					{
+						"str": "b",
+						"strs": [
+							"c",
+							"d"
+						]
+					}
+				]
+			}
+		`)
+
+		runErr([]string{"str+=a"}, "unmarshal error for arg #1 str+ with value a: cannot append to str, it is not a repeated field")
+		runErr([]string{"strs.2=a"}, "unmarshal error for arg #1 strs.2 with value a: missing index 2 in array")
+		runErr([]string{"strs.[].a=a"}, "unmarshal error for arg #1 strs.[].a with value a: cannot set nested field a")
+	})
+
+	t.Run("Map", func(t *testing.T) {
+		run([]string{
+			"labels.env=prod",
+			"labels.team=core",
+			"limits.cpu.min=1",
+			"limits.cpu.max=4",
+			"limits.memory.max=1024",
+			"enum_map.a=enum_value2",
+			"int32_map.-32=a",
+			"int64_map.64=a",
+			"uint32_map.32=a",
+			"uint64_map.64=a",
+			"sint32_map.-32=a",
+			"sint64_map.-64=a",
+			"fixed32_map.32=a",
+			"fixed64_map.64=a",
+			"sfixed32_map.-32=a",
+			"sfixed64_map.-64=a",
+			"bool_map.true=a",
+		}, `
+			{
+				"labels": {
+					"env": "prod",
+					"team": "core"
+				},
+				"limits": {
+					"cpu": {
+						"min": "1",
+						"max": "4"
+					},
+					"memory": {
+						"max": "1024"
+					}
+				},
+				"enum_map": {
+					"a": "enum_value2"
+				},
+				"int32_map": {
+					"-32": "a"
+				},
+				"int64_map": {
+					"64": "a"
+				},
+				"uint32_map": {
+					"32": "a"
+				},
+				"uint64_map": {
+					"64": "a"
+				},
+				"sint32_map": {
+					"-32": "a"
+				},
+				"sint64_map": {
+					"-64": "a"
+				},
+				"fixed32_map": {
+					"32": "a"
+				},
+				"fixed64_map": {
+					"64": "a"
+				},
+				"sfixed32_map": {
+					"-32": "a"
+				},
+				"sfixed64_map": {
+					"-64": "a"
+				},
+				"bool_map": {
+					"true": "a"
+				}
+			}
+		`)
+	})
+
+	t.Run("Map errors", func(t *testing.T) {
+		runErr([]string{"labels=prod"}, "unmarshal error for arg #1 labels with value prod: missing map key")
+		runErr([]string{"int32_map.abc=a"}, `unmarshal error for arg #1 int32_map.abc with value a: invalid map key abc: strconv.ParseInt: parsing "abc": invalid syntax`)
+		runErr([]string{"bool_map.yes=a"}, "unmarshal error for arg #1 bool_map.yes with value a: invalid map key yes: yes is not a valid boolean a=value")
+		runErr([]string{"labels.env.name=prod"}, "unmarshal error for arg #1 labels.env.name with value prod: cannot set nested field name")
+	})
+
+}
+
+// testNow is the clock used to evaluate relative timestamps in tests.
+func testNow() time.Time {
+	return time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)
+}
+
+// newTestTypes registers every message of the test files so that google.protobuf.Any can be resolved.
+func newTestTypes(t *testing.T, files *protoregistry.Files) *protoregistry.Types {
+	types := &protoregistry.Types{}
+	var registerMessages func(messages protoreflect.MessageDescriptors)
+	registerMessages = func(messages protoreflect.MessageDescriptors) {
+		for i := 0; i < messages.Len(); i++ {
+			require.NoError(t, types.RegisterMessage(dynamicpb.NewMessageType(messages.Get(i))))
+			registerMessages(messages.Get(i).Messages())
+		}
+	}
+	files.RangeFiles(func(file protoreflect.FileDescriptor) bool {
+		registerMessages(file.Messages())
+		return true
+	})
+	return types
 }
 
 func formatExpected(str string) string {
@@ -17,6 +17,13 @@ type Config struct {
 const (
 	DefaultProfileName = "default"
 	DefaultConfigPath  = "~/.config/grpc-cli/config.yaml"
+
+	// DescriptorReflection is the descriptor value used to fetch descriptors with gRPC server reflection.
+	DescriptorReflection = "reflection"
+
+	// ArgNamingProto and ArgNamingJSON are the naming styles of args in usage and autocompletion.
+	ArgNamingProto = "proto"
+	ArgNamingJSON  = "json"
 )
 
 func LoadProfile(configPath string, profileName string) (Profile, error) {
@@ -50,8 +57,13 @@ func (p Profile) Merge(p2 Profile) Profile {
 	if p2.Target != nil {
 		newProfile.Target = p2.Target
 	}
-	if p2.Descriptor != nil {
-		newProfile.Descriptor = p2.Descriptor
+	// Descriptors and proto files are alternative sources of the same descriptors, setting one replaces both.
+	if len(p2.Descriptors) > 0 || len(p2.Proto) > 0 {
+		newProfile.Descriptors = p2.Descriptors
+		newProfile.Proto = p2.Proto
+	}
+	if len(p2.ImportPaths) > 0 {
+		newProfile.ImportPaths = p2.ImportPaths
 	}
 	if p2.CaCert != nil {
 		newProfile.CaCert = p2.CaCert
@@ -65,6 +77,18 @@ func (p Profile) Merge(p2 Profile) Profile {
 	if p2.DisableTLS != nil {
 		newProfile.DisableTLS = p2.DisableTLS
 	}
+	if p2.ArgNaming != nil {
+		newProfile.ArgNaming = p2.ArgNaming
+	}
+	if len(p2.Vars) > 0 {
+		newProfile.Vars = make(map[string]string, len(p.Vars)+len(p2.Vars))
+		for k, v := range p.Vars {
+			newProfile.Vars[k] = v
+		}
+		for k, v := range p2.Vars {
+			newProfile.Vars[k] = v
+		}
+	}
 
 	if newProfile.Metadata == nil && len(p2.Metadata) > 0 {
 		newProfile.Metadata = make(metadata.MD)
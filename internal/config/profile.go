@@ -9,31 +9,112 @@ import (
 
 	"github.com/jerome-quere/grpc-cli/internal/util"
 	"google.golang.org/grpc/metadata"
+	"gopkg.in/yaml.v3"
 )
 
 type Profile struct {
-	Target     *string     `yaml:"target"`
-	Descriptor *string     `yaml:"descriptor"`
-	Metadata   metadata.MD `yaml:"metadata"`
-	CaCert     *string     `yaml:"ca_cert"`
-	Cert       *string     `yaml:"cert"`
-	Key        *string     `yaml:"key"`
-	DisableTLS *bool       `yaml:"disable_tls"`
+	Target      *string     `yaml:"target"`
+	Descriptors StringList  `yaml:"descriptor"`
+	Proto       []string    `yaml:"proto"`
+	ImportPaths []string    `yaml:"import_paths"`
+	Metadata    metadata.MD `yaml:"metadata"`
+	CaCert      *string     `yaml:"ca_cert"`
+	Cert        *string     `yaml:"cert"`
+	Key         *string     `yaml:"key"`
+	DisableTLS  *bool       `yaml:"disable_tls"`
+	ArgNaming   *string     `yaml:"arg_naming"`
+
+	// Vars are variables that can be used in args, metadata and config strings using ${NAME}.
+	Vars map[string]string `yaml:"vars"`
 }
 
 func (p Profile) Validate() error {
 	switch {
 	case p.Target == nil:
 		return fmt.Errorf("target cannot be empty, you must set it in the config file or pass it as argument")
-	case p.Descriptor == nil:
-		return fmt.Errorf("descriptor cannot be empty, you must set it in the config file or pass it as argument")
+	case len(p.Descriptors) > 1 && p.Descriptors.contains(DescriptorReflection):
+		return fmt.Errorf("descriptor %s cannot be combined with other descriptors", DescriptorReflection)
+	case len(p.Proto) > 0 && p.Descriptors.contains(DescriptorReflection):
+		return fmt.Errorf("descriptor %s cannot be combined with proto files", DescriptorReflection)
+	case p.ArgNaming != nil && *p.ArgNaming != ArgNamingProto && *p.ArgNaming != ArgNamingJSON:
+		return fmt.Errorf("invalid arg naming %s, must be %s or %s", *p.ArgNaming, ArgNamingProto, ArgNamingJSON)
 	default:
 		return nil
 	}
 }
 
-func (p Profile) GetDescriptor() string {
-	return util.ResolvePath(*p.Descriptor)
+// UseReflection returns true if descriptors must be fetched using gRPC server reflection.
+// This is the case when no descriptor nor proto is configured or when descriptor is set to "reflection".
+func (p Profile) UseReflection() bool {
+	if len(p.Descriptors) == 0 {
+		return len(p.Proto) == 0
+	}
+	return len(p.Descriptors) == 1 && p.Descriptors[0] == DescriptorReflection
+}
+
+func (p Profile) GetDescriptors() []string {
+	return resolvePaths(p.Descriptors)
+}
+
+func (p Profile) GetProto() []string {
+	return resolvePaths(p.Proto)
+}
+
+func (p Profile) GetImportPaths() []string {
+	return resolvePaths(p.ImportPaths)
+}
+
+// GetArgNaming returns the naming style of args in usage and autocompletion. Proto names are used by default.
+func (p Profile) GetArgNaming() string {
+	if p.ArgNaming == nil {
+		return ArgNamingProto
+	}
+	return *p.ArgNaming
+}
+
+// Interpolate returns a copy of the profile where interpolate has been applied to all strings but vars.
+func (p Profile) Interpolate(interpolate func(string) (string, error)) (Profile, error) {
+	var err error
+	interpolateString := func(str *string) *string {
+		if str == nil || err != nil {
+			return str
+		}
+		res, interpolateErr := interpolate(*str)
+		if interpolateErr != nil {
+			err = interpolateErr
+		}
+		return &res
+	}
+	interpolateList := func(strs []string) []string {
+		if strs == nil {
+			return nil
+		}
+		res := make([]string, 0, len(strs))
+		for i := range strs {
+			res = append(res, *interpolateString(&strs[i]))
+		}
+		return res
+	}
+
+	newProfile := p
+	newProfile.Target = interpolateString(p.Target)
+	newProfile.Descriptors = interpolateList(p.Descriptors)
+	newProfile.Proto = interpolateList(p.Proto)
+	newProfile.ImportPaths = interpolateList(p.ImportPaths)
+	newProfile.CaCert = interpolateString(p.CaCert)
+	newProfile.Cert = interpolateString(p.Cert)
+	newProfile.Key = interpolateString(p.Key)
+	newProfile.ArgNaming = interpolateString(p.ArgNaming)
+	if p.Metadata != nil {
+		newProfile.Metadata = make(metadata.MD, len(p.Metadata))
+		for k, v := range p.Metadata {
+			newProfile.Metadata[k] = interpolateList(v)
+		}
+	}
+	if err != nil {
+		return Profile{}, err
+	}
+	return newProfile, nil
 }
 
 func (p Profile) GetDisableTLS() bool {
@@ -78,3 +159,31 @@ func (p Profile) String() string {
 	}
 	return string(res)
 }
+
+// StringList is a list of strings that can also be written as a single string in the config file.
+type StringList []string
+
+func (l *StringList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*l = StringList{value.Value}
+		return nil
+	}
+	return value.Decode((*[]string)(l))
+}
+
+func (l StringList) contains(str string) bool {
+	for _, item := range l {
+		if item == str {
+			return true
+		}
+	}
+	return false
+}
+
+func resolvePaths(paths []string) []string {
+	res := make([]string, 0, len(paths))
+	for _, path := range paths {
+		res = append(res, util.ResolvePath(path))
+	}
+	return res
+}
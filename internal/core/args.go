@@ -0,0 +1,86 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jerome-quere/grpc-cli/internal/args"
+	"github.com/jerome-quere/grpc-cli/internal/interpolate"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Words made of these characters don't need to be quoted in a shell.
+var shellSafeRegexp = regexp.MustCompile(`^[a-zA-Z0-9_@%+=:,./-]+$`)
+
+// ArgsCobraCommand converts a JSON or YAML request to the args of the equivalent rpc command.
+func ArgsCobraCommand(ctx context.Context, files *protoregistry.Files) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "args <service> <method>",
+		Short: "Convert a JSON or YAML request to rpc args",
+		Args: func(cmd *cobra.Command, rawArgs []string) error {
+			if len(rawArgs) != 2 {
+				return withExitCode(ExitCodeInvalidArgs, fmt.Errorf("expected a service and a method, got %d args", len(rawArgs)))
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, rawArgs []string) error {
+			serviceName, methodName := rawArgs[0], rawArgs[1]
+			desc, err := files.FindDescriptorByName(protoreflect.FullName(serviceName + "." + methodName))
+			if err != nil {
+				return withExitCode(ExitCodeInvalidArgs, fmt.Errorf("unknown method %s.%s", serviceName, methodName))
+			}
+			method, isMethod := desc.(protoreflect.MethodDescriptor)
+			if !isMethod {
+				return withExitCode(ExitCodeInvalidArgs, fmt.Errorf("%s.%s is not a method", serviceName, methodName))
+			}
+
+			data, err := cmd.Flags().GetString("data")
+			if err != nil {
+				return err
+			}
+			raw, err := readRequestData(ctx, data)
+			if err != nil {
+				return withExitCode(ExitCodeInvalidArgs, err)
+			}
+			req := dynamicpb.NewMessage(method.Input())
+			err = unmarshalRequestData(ctx, raw, req)
+			if err != nil {
+				return withExitCode(ExitCodeInvalidArgs, err)
+			}
+
+			reqArgs, err := args.MarshalOptions{
+				Resolver: newTypeResolver(CtxFiles(ctx)),
+				Naming:   CtxArgNaming(ctx),
+			}.Marshal(req)
+			if err != nil {
+				return withExitCode(ExitCodeInvalidArgs, fmt.Errorf("cannot convert request to args: %w", err))
+			}
+
+			// rpc interpolates its args, values that look like placeholders must be escaped.
+			words := []string{CtxBinaryName(ctx), "rpc", serviceName, methodName}
+			for _, arg := range reqArgs {
+				words = append(words, interpolate.Escape(arg))
+			}
+			for i, word := range words {
+				words[i] = shellQuote(word)
+			}
+			_, err = fmt.Fprintln(CtxStdout(ctx), strings.Join(words, " "))
+			return err
+		},
+	}
+	cmd.Flags().StringP("data", "d", "@-", "Request in JSON or YAML. Use @path to read it from a file. Read from stdin by default")
+	return cmd
+}
+
+// shellQuote quotes a word so it can be pasted in a POSIX shell.
+func shellQuote(word string) string {
+	if shellSafeRegexp.MatchString(word) {
+		return word
+	}
+	return "'" + strings.ReplaceAll(word, "'", `'\''`) + "'"
+}
@@ -0,0 +1,53 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestArgs(t *testing.T) {
+
+	t.Run("stdin", Test(&TestConfig{
+		Descriptor: rawProto,
+		Cmd:        "grpc-cli args test.Api Echo",
+		Stdin:      `{"str": "it's a test", "nested": {"strs": ["a", "b"]}, "labels": {"a.b": "c"}, "duration": "1.5s", "any": {"@type": "type.googleapis.com/test.Simple.Nested", "str": "x"}}`,
+		Check:      TestCheckGolden(),
+	}))
+
+	t.Run("placeholders", Test(&TestConfig{
+		Descriptor: rawProto,
+		Cmd:        "grpc-cli args test.Api Echo",
+		Stdin:      `{"str": "${HOME}", "strs": ["{{ .env.HOME }}"]}`,
+		Check:      TestCheckGolden(),
+	}))
+
+	t.Run("yaml file", Test(&TestConfig{
+		Descriptor: rawProto,
+		Cmd:        "grpc-cli args test.Api Echo -d @testdata/request.yaml",
+		Check:      TestCheckGolden(),
+	}))
+
+	t.Run("yaml scalars", Test(&TestConfig{
+		Descriptor: rawProto,
+		Cmd:        "grpc-cli args test.Api Echo -d @testdata/request-scalars.yaml",
+		Check:      TestCheckGolden(),
+	}))
+
+	t.Run("json naming", Test(&TestConfig{
+		Descriptor: rawProto,
+		Cmd:        "grpc-cli --arg-naming json args test.Api Echo -d @testdata/request.yaml",
+		Check:      TestCheckGolden(),
+	}))
+
+	t.Run("unknown method", Test(&TestConfig{
+		Descriptor: rawProto,
+		Cmd:        "grpc-cli args test.Api Unknown",
+		Check:      TestCheckGolden(),
+	}))
+
+	t.Run("invalid body", Test(&TestConfig{
+		Descriptor: rawProto,
+		Cmd:        "grpc-cli args test.Api Echo",
+		Stdin:      `{"unknown": 1}`,
+		Check:      TestCheckGolden(),
+	}))
+}
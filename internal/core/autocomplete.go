@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/jerome-quere/grpc-cli/internal/args"
 	"github.com/spf13/cobra"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/reflect/protoregistry"
@@ -28,13 +29,13 @@ func Autocomplete(ctx context.Context, files *protoregistry.Files, leftWords []s
 	}
 
 	if len(leftWords) > 3 {
-		return autocompleteParams(files, leftWords[2], leftWords[3], wordToComplete)
+		return autocompleteParams(files, leftWords[2], leftWords[3], wordToComplete, CtxArgNaming(ctx))
 	}
 
 	return nil
 }
 
-func autocompleteParams(files *protoregistry.Files, serviceName string, methodName string, wordToComplete string) []string {
+func autocompleteParams(files *protoregistry.Files, serviceName string, methodName string, wordToComplete string, naming args.NamingStyle) []string {
 	desc, err := files.FindDescriptorByName(protoreflect.FullName(serviceName + "." + methodName))
 	if err != nil {
 		return nil
@@ -45,15 +46,35 @@ func autocompleteParams(files *protoregistry.Files, serviceName string, methodNa
 	fields := reqDesc.Fields()
 	res := []string(nil)
 
+	if strings.Contains(wordToComplete, "=") {
+		tmp := strings.SplitN(wordToComplete, "=", 2)
+		return autocompleteEnumValue(reqDesc, tmp[0], tmp[1])
+	}
+
 	for i := 0; i < fields.Len(); i++ {
 		field := fields.Get(i)
-		if strings.HasPrefix(string(field.Name()), wordToComplete) {
-			res = append(res, string(field.Name())+"=")
+		argName := args.ArgName(field, naming)
+		if strings.HasPrefix(argName, wordToComplete) {
+			res = append(res, argName+"=")
 		}
 	}
 	return res
 }
 
+// autocompleteEnumValue suggests the values of an enum arg (e.g. enum=value1).
+func autocompleteEnumValue(message protoreflect.MessageDescriptor, argName string, wordToComplete string) []string {
+	field, err := args.FindField(message, argName)
+	if err != nil || field.Enum() == nil || field.IsMap() {
+		return nil
+	}
+
+	res := []string(nil)
+	for _, name := range autocompleteFilter(args.EnumValueNames(field.Enum()), wordToComplete) {
+		res = append(res, argName+"="+name)
+	}
+	return res
+}
+
 func autocompleteFilter(strs []string, wordToComplete string) []string {
 	res := []string(nil)
 	for _, s := range strs {
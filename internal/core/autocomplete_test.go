@@ -6,6 +6,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/jerome-quere/grpc-cli/internal/args"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/protobuf/proto"
@@ -25,7 +26,7 @@ func Test_autocomplete(t *testing.T) {
 	files, err := protodesc.NewFiles(&fileDescSet)
 	require.NoError(t, err)
 
-	ctx := context.Background()
+	ctx := ctxInjectData(context.Background(), &contextData{})
 
 	run := func(tc TestCase) func(t *testing.T) {
 		return func(t *testing.T) {
@@ -45,7 +46,17 @@ func Test_autocomplete(t *testing.T) {
 
 	t.Run("grpc-cli ", run(TestCase{Suggestions: []string{"rpc"}}))
 	t.Run("grpc-cli rpc tes", run(TestCase{Suggestions: []string{"test.Api"}}))
-	t.Run("grpc-cli rpc test.Api ", run(TestCase{Suggestions: []string{"Echo"}}))
-	t.Run("grpc-cli rpc test.Api Echo s", run(TestCase{Suggestions: []string{"str=", "strs="}}))
-	t.Run("grpc-cli rpc test.Api Echo u", run(TestCase{Suggestions: []string{"uint32=", "uint64="}}))
+	t.Run("grpc-cli rpc test.Api ", run(TestCase{Suggestions: []string{"Echo", "ServerStream", "ClientStream", "BidiStream"}}))
+	t.Run("grpc-cli rpc test.Api Echo s", run(TestCase{Suggestions: []string{"str=", "sint32=", "sint64=", "sfixed32=", "sfixed64=", "struct=", "strs=", "sint32_map=", "sint64_map=", "sfixed32_map=", "sfixed64_map="}}))
+	t.Run("grpc-cli rpc test.Api Echo u", run(TestCase{Suggestions: []string{"uint32=", "uint64=", "uint32_map=", "uint64_map="}}))
+	t.Run("grpc-cli rpc test.Api Echo enum=", run(TestCase{Suggestions: []string{"enum=value1", "enum=value2"}}))
+	t.Run("grpc-cli rpc test.Api Echo enums=value2", run(TestCase{Suggestions: []string{"enums=value2"}}))
+	t.Run("grpc-cli rpc test.Api Echo Enum=", run(TestCase{Suggestions: []string{"Enum=value1", "Enum=value2"}}))
+	t.Run("grpc-cli rpc test.Api Echo str=", run(TestCase{Suggestions: nil}))
+
+	t.Run("json naming", func(t *testing.T) {
+		ctx := ctxInjectData(context.Background(), &contextData{ArgNaming: args.NamingStyleJSON})
+		suggestion := Autocomplete(ctx, files, []string{"grpc-cli", "rpc", "test.Api", "Echo"}, "uint", nil)
+		assert.Equal(t, []string{"uint32=", "uint64=", "uint32Map=", "uint64Map="}, suggestion)
+	})
 }
@@ -2,17 +2,14 @@ package core
 
 import (
 	"context"
-	"fmt"
 	"io"
-	"io/ioutil"
 	"time"
 
+	"github.com/jerome-quere/grpc-cli/internal/args"
 	"github.com/jerome-quere/grpc-cli/internal/config"
+	"github.com/jerome-quere/grpc-cli/internal/interpolate"
 	"github.com/sirupsen/logrus"
-	"google.golang.org/protobuf/proto"
-	"google.golang.org/protobuf/reflect/protodesc"
 	"google.golang.org/protobuf/reflect/protoregistry"
-	"google.golang.org/protobuf/types/descriptorpb"
 )
 
 type BootstrapConfig struct {
@@ -24,6 +21,10 @@ type BootstrapConfig struct {
 	// Usefull fot test to provide a Descriptor directly.
 	// If this is set this will bypass default Descriptor loading
 	Descriptor []byte
+
+	// Directory where remote descriptors and call outputs are cached.
+	// Defaults to the cache directory next to the config file.
+	CacheDir string
 }
 
 const defaultDialTimeout = time.Second * 10
@@ -59,41 +60,39 @@ func Bootstrap(ctx context.Context, bootstrapConfig *BootstrapConfig) int {
 	profile, err := config.LoadProfile(flags.Config, flags.Profile)
 	if err != nil {
 		logger.Errorf("cannot load profile: %s", err)
-		return 1
+		return ExitCodeInvalidConfig
 	}
 	// We merge profile params passed as flag on top of the config profile
 	profile = profile.Merge(flags.GetProfile())
 
+	//
+	// Resolve placeholders in profile strings using env, profile vars and the output of the last call
+	//
+	cacheDir := bootstrapConfig.CacheDir
+	if cacheDir == "" {
+		cacheDir = defaultCacheDir(flags.Config)
+	}
+	interpolationData, err := buildInterpolationData(logger, profile, cacheDir)
+	if err != nil {
+		logger.Errorf("cannot load variables: %s", err)
+		return ExitCodeInvalidConfig
+	}
+	profile, err = profile.Interpolate(func(str string) (string, error) {
+		return interpolate.Interpolate(str, interpolationData)
+	})
+	if err != nil {
+		logger.Errorf("cannot interpolate profile: %s", err)
+		return ExitCodeInvalidConfig
+	}
+
 	// Validate profile to make sure all required fields are set
 	err = profile.Validate()
 	if err != nil {
 		logger.Errorf("error while validating profile: %s", err)
-		return 1
+		return ExitCodeInvalidConfig
 	}
 	logger.Debugf("Loading profile complete: %s", profile)
 
-	//
-	// Loading proto descriptor file
-	//
-	var files *protoregistry.Files
-	if bootstrapConfig.Descriptor == nil {
-		logger.Debugf("Loading descriptor from file: %s", profile.GetDescriptor())
-		files, err = loadDescriptorFromFile(profile.GetDescriptor())
-		if err != nil {
-			logger.Errorf("cannot load descriptor file: %s\n", err)
-			return 1
-		}
-		logger.Debugf("Loading descriptor complete: %d proto files found", files.NumFiles())
-	} else {
-		logger.Debugf("Loading descriptor from bootstrap config")
-		files, err = loadDescriptorFromBytes(bootstrapConfig.Descriptor)
-		if err != nil {
-			logger.Errorf("cannot load descriptor file: %s\n", err)
-			return 1
-		}
-		logger.Debugf("Loading descriptor complete: %d proto files found", files.NumFiles())
-	}
-
 	//
 	// Building gRPC dial config for the gRPC connection
 	//
@@ -101,7 +100,7 @@ func Bootstrap(ctx context.Context, bootstrapConfig *BootstrapConfig) int {
 	tlsConfig, err := profile.GetTLSConfig()
 	if err != nil {
 		logger.Errorf("Cannont load TLS config: %s", err)
-		return 1
+		return ExitCodeInvalidConfig
 	}
 	dialConfig := &DialConfig{
 		TLSConfig: tlsConfig,
@@ -121,8 +120,12 @@ func Bootstrap(ctx context.Context, bootstrapConfig *BootstrapConfig) int {
 		Stdout:     bootstrapConfig.Stdout,
 		Stderr:     bootstrapConfig.Stderr,
 		MD:         profile.Metadata,
+		ArgNaming:  args.NamingStyle(profile.GetArgNaming()),
 		Logger:     logger,
 		DialConfig: dialConfig,
+		CacheDir:   cacheDir,
+
+		InterpolationData: interpolationData,
 
 		// We do not open connection now as we are not sure we need it yet.
 		// gRPC connection will only be opened when required.
@@ -136,11 +139,33 @@ func Bootstrap(ctx context.Context, bootstrapConfig *BootstrapConfig) int {
 		}
 	}()
 
+	//
+	// Loading proto descriptor file
+	//
+	var files *protoregistry.Files
+	switch {
+	case bootstrapConfig.Descriptor != nil:
+		logger.Debugf("Loading descriptor from bootstrap config")
+		files, err = loadDescriptorFromBytes(bootstrapConfig.Descriptor)
+	case profile.UseReflection():
+		logger.Debugf("Loading descriptor using server reflection from: %s", profile.GetTarget())
+		files, err = loadDescriptorFromReflection(ctx, descriptorCacheDir(cacheDir), isHelpRequest(bootstrapConfig.Args, flags.Args()))
+	default:
+		logger.Debugf("Loading descriptors: %s and compiling proto files: %s", profile.GetDescriptors(), profile.GetProto())
+		files, err = loadDescriptors(ctx, profile.GetDescriptors(), profile.GetProto(), profile.GetImportPaths(), descriptorCacheDir(cacheDir))
+	}
+	if err != nil {
+		logger.Errorf("cannot load descriptor: %s\n", err)
+		return exitCodeFromError(withExitCode(ExitCodeDescriptor, err))
+	}
+	logger.Debugf("Loading descriptor complete: %d proto files found", files.NumFiles())
+	ctxData.Files = files
+
 	// Build cobra command
 	rootCmd, err := buildCobraCommand(ctx, files)
 	if err != nil {
 		logger.Errorf("cannot build cobra command: %s", err)
-		return 1
+		return ExitCodeError
 	}
 
 	rootCmd.PersistentFlags().AddFlagSet(flags.FlagSet)
@@ -148,31 +173,31 @@ func Bootstrap(ctx context.Context, bootstrapConfig *BootstrapConfig) int {
 	err = rootCmd.Execute()
 	if err != nil {
 		logger.Errorf("error when executing cmd: %s\n", err)
-		return 1
+		return exitCodeFromError(err)
 	}
 
-	return 0
+	return ExitCodeSuccess
 }
 
-func loadDescriptorFromFile(path string) (*protoregistry.Files, error) {
-
-	descriptorRaw, err := ioutil.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("cannont open file %s: %w", path, err)
-	}
-	return loadDescriptorFromBytes(descriptorRaw)
-}
-
-func loadDescriptorFromBytes(descriptorRaw []byte) (*protoregistry.Files, error) {
-	var fileDescSet descriptorpb.FileDescriptorSet
-	err := proto.Unmarshal(descriptorRaw, &fileDescSet)
-	if err != nil {
-		return nil, fmt.Errorf("cannot parse descriptor set: %w", err)
+// isHelpRequest returns true if the command only prints help or completions (e.g. -h, autocomplete, rpc without method).
+// positionalArgs are the args left by the first flag parsing, starting with the binary name.
+func isHelpRequest(rawArgs []string, positionalArgs []string) bool {
+	for _, arg := range rawArgs[1:] {
+		if arg == "--" {
+			break
+		}
+		if arg == "-h" || arg == "--help" {
+			return true
+		}
 	}
 
-	files, err := protodesc.NewFiles(&fileDescSet)
-	if err != nil {
-		return nil, fmt.Errorf("\"cannot create proto reflect struct: %w", err)
+	positionalArgs = positionalArgs[1:]
+	switch {
+	case len(positionalArgs) == 0:
+		return true
+	case positionalArgs[0] == "help" || positionalArgs[0] == "autocomplete":
+		return true
+	default:
+		return positionalArgs[0] == "rpc" && len(positionalArgs) < 3
 	}
-	return files, nil
 }
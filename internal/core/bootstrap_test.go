@@ -34,4 +34,98 @@ func TestBootstrap_usage(t *testing.T) {
 		Check:      TestCheckGolden(),
 	}))
 
+	t.Run("rpc test.Api Echo json naming", Test(&TestConfig{
+		Descriptor: rawProto,
+		Cmd:        "grpc-cli --arg-naming json rpc test.Api Echo -h",
+		Check:      TestCheckGolden(),
+	}))
+
+	t.Run("invalid arg naming", Test(&TestConfig{
+		Descriptor: rawProto,
+		Cmd:        "grpc-cli --arg-naming kebab rpc test.Api Echo -h",
+		Check:      TestCheckGolden(),
+	}))
+}
+
+func TestBootstrap_proto(t *testing.T) {
+
+	t.Run("proto file", Test(&TestConfig{
+		Server: registerTestApi(t),
+		Cmd:    "grpc-cli --proto ../../protobuf/test/test.proto -I ../../protobuf rpc test.Api Echo str=abc wrapper_str=def",
+		Check:  TestCheckGolden(),
+	}))
+
+	t.Run("descriptor directory", Test(&TestConfig{
+		Cmd:   "grpc-cli --descriptor ../../protobuf rpc test.Api -h",
+		Check: TestCheckGolden(),
+	}))
+
+	t.Run("unknown file", Test(&TestConfig{
+		Cmd:   "grpc-cli --proto ../../protobuf/test/unknown.proto rpc test.Api -h",
+		Check: TestCheckGolden(),
+	}))
+
+	t.Run("descriptor flag overrides config proto", Test(&TestConfig{
+		Cmd:   "grpc-cli -c testdata/config-proto.yaml --descriptor ../../protobuf rpc test.Api -h",
+		Check: TestCheckGolden(),
+	}))
+
+	t.Run("descriptor and proto", Test(&TestConfig{
+		Cmd:   "grpc-cli -c testdata/config-descriptor-proto.yaml rpc -h",
+		Check: TestCheckGolden(),
+	}))
+
+	t.Run("reflection and proto", Test(&TestConfig{
+		Cmd:   "grpc-cli -c testdata/config-descriptor-proto.yaml -p reflection rpc -h",
+		Check: TestCheckCombine(TestCheckExitCode(ExitCodeInvalidConfig), TestCheckGolden()),
+	}))
+
+	t.Run("proto flag overrides profile descriptor", Test(&TestConfig{
+		Cmd:   "grpc-cli -c testdata/config-proto.yaml -p reflection --proto ../../protobuf/test/test.proto -I ../../protobuf rpc test.Api -h",
+		Check: TestCheckGolden(),
+	}))
+}
+
+func TestBootstrap_exitCode(t *testing.T) {
+
+	t.Run("invalid args", Test(&TestConfig{
+		Descriptor: rawProto,
+		Cmd:        "grpc-cli rpc test.Api Echo unknown=1",
+		Check:      TestCheckCombine(TestCheckExitCode(ExitCodeInvalidArgs), TestCheckGolden()),
+	}))
+
+	t.Run("invalid flag", Test(&TestConfig{
+		Descriptor: rawProto,
+		Cmd:        "grpc-cli rpc test.Api Echo --unknown",
+		Check:      TestCheckCombine(TestCheckExitCode(ExitCodeInvalidArgs), TestCheckGolden()),
+	}))
+
+	t.Run("unknown command", Test(&TestConfig{
+		Descriptor: rawProto,
+		Cmd:        "grpc-cli foo",
+		Check:      TestCheckCombine(TestCheckExitCode(ExitCodeInvalidArgs), TestCheckGolden()),
+	}))
+
+	t.Run("unknown service", Test(&TestConfig{
+		Descriptor: rawProto,
+		Cmd:        "grpc-cli rpc test.Unknown",
+		Check:      TestCheckCombine(TestCheckExitCode(ExitCodeInvalidArgs), TestCheckGolden()),
+	}))
+
+	t.Run("unknown method", Test(&TestConfig{
+		Descriptor: rawProto,
+		Cmd:        "grpc-cli rpc test.Api Unknown",
+		Check:      TestCheckCombine(TestCheckExitCode(ExitCodeInvalidArgs), TestCheckGolden()),
+	}))
+
+	t.Run("reflection with descriptors", Test(&TestConfig{
+		Cmd:   "grpc-cli --target localhost:50051 --descriptor reflection --descriptor testdata/test.pb rpc test.Api -h",
+		Check: TestCheckCombine(TestCheckExitCode(ExitCodeInvalidConfig), TestCheckGolden()),
+	}))
+
+	t.Run("invalid config", Test(&TestConfig{
+		Descriptor: rawProto,
+		Cmd:        "grpc-cli -c testdata/test.pb rpc test.Api Echo",
+		Check:      TestCheckCombine(TestCheckExitCode(ExitCodeInvalidConfig), TestCheckGolden()),
+	}))
 }
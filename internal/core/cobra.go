@@ -9,11 +9,8 @@ import (
 
 	"github.com/jerome-quere/grpc-cli/internal/args"
 	"github.com/spf13/cobra"
-	"google.golang.org/grpc/metadata"
-	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/reflect/protoregistry"
-	"google.golang.org/protobuf/types/dynamicpb"
 )
 
 func buildCobraCommand(ctx context.Context, files *protoregistry.Files) (*cobra.Command, error) {
@@ -25,8 +22,17 @@ func buildCobraCommand(ctx context.Context, files *protoregistry.Files) (*cobra.
 
 		// Do not display usage on error.
 		SilenceUsage: true,
+
+		Args: subcommandArgs("command"),
+		RunE: runHelp,
 	}
+	// Commands grouping subcommands only run to print their help, their usage line is not shown.
+	rootCmd.SetUsageTemplate(strings.Replace(rootCmd.UsageTemplate(), "{{if .Runnable}}", "{{if and .Runnable (not .HasAvailableSubCommands)}}", 1))
 	rootCmd.SetOut(CtxStderr(ctx))
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return withExitCode(ExitCodeInvalidArgs, err)
+	})
+	rootCmd.AddCommand(ArgsCobraCommand(ctx, files))
 	rootCmd.AddCommand(AutocompleteCobraCommand(ctx, files))
 	rootCmd.AddCommand(RpcCobraCommand(ctx, files))
 	return rootCmd, nil
@@ -37,14 +43,19 @@ func RpcCobraCommand(ctx context.Context, files *protoregistry.Files) *cobra.Com
 	cmd := &cobra.Command{
 		Use:   "rpc",
 		Short: "Execute an rpc call",
+		Args:  subcommandArgs("service"),
+		RunE:  runHelp,
 	}
+	cmd.PersistentFlags().Bool("print-metadata", false, "Print response headers and trailers on stderr")
 
 	files.RangeFiles(func(file protoreflect.FileDescriptor) bool {
 		for i := 0; i < file.Services().Len(); i++ {
 			service := file.Services().Get(i)
 
 			serviceCmd := &cobra.Command{
-				Use: string(service.FullName()),
+				Use:  string(service.FullName()),
+				Args: subcommandArgs("method"),
+				RunE: runHelp,
 			}
 
 			for i := 0; i < service.Methods().Len(); i++ {
@@ -53,6 +64,11 @@ func RpcCobraCommand(ctx context.Context, files *protoregistry.Files) *cobra.Com
 					Use:  string(method.Name()),
 					RunE: rpcRun(ctx, method),
 				}
+				if method.IsStreamingClient() {
+					methodCmd.Flags().StringP("input", "i", "", "File containing newline-delimited requests (JSON or key=value args). Read from stdin by default")
+				} else {
+					methodCmd.Flags().StringP("data", "d", "", "Request body in JSON or YAML. Use @path to read it from a file or @- to read it from stdin. Args override its fields")
+				}
 				methodCmd.SetUsageTemplate(usageTemplate)
 				methodCmd.Annotations = make(map[string]string)
 				methodCmd.Annotations["UsageArgs"] = buildUsageArgs(ctx, method.Input())
@@ -68,23 +84,51 @@ func RpcCobraCommand(ctx context.Context, files *protoregistry.Files) *cobra.Com
 	return cmd
 }
 
+// subcommandArgs rejects the args of a command that only groups subcommands.
+// Without it, cobra prints the help of the parent command and succeeds when a subcommand is unknown.
+func subcommandArgs(kind string) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, rawArgs []string) error {
+		if len(rawArgs) > 0 {
+			return withExitCode(ExitCodeInvalidArgs, fmt.Errorf("unknown %s %q for %q", kind, rawArgs[0], cmd.CommandPath()))
+		}
+		return nil
+	}
+}
+
+// runHelp prints the help of a command that only groups subcommands.
+func runHelp(cmd *cobra.Command, _ []string) error {
+	return cmd.Help()
+}
+
 func buildUsageArgs(ctx context.Context, message protoreflect.MessageDescriptor) string {
 	var argsBuffer bytes.Buffer
 	tw := tabwriter.NewWriter(&argsBuffer, 0, 0, 3, ' ', 0)
 
+	naming := CtxArgNaming(ctx)
+	lines := [][2]string(nil)
+	printedOneofs := map[protoreflect.FullName]bool{}
 	for i := 0; i < message.Fields().Len(); i++ {
 		field := message.Fields().Get(i)
-		argSpecUsageLeftPart := string(field.Name())
-		argSpecUsageRightPart := field.Kind().String()
-		if enum := field.Enum(); enum != nil {
-			names := []string(nil)
-			for i := 0; i < enum.Values().Len(); i++ {
-				names = append(names, string(enum.Values().Get(i).Name()))
-			}
-			argSpecUsageRightPart += "(" + strings.Join(names, ", ") + ")"
+
+		// Members of a oneof are grouped together so users see they are alternatives.
+		oneof := field.ContainingOneof()
+		if oneof == nil || oneof.IsSynthetic() {
+			lines = append(lines, [2]string{"  " + args.ArgName(field, naming), usageArgType(field)})
+			continue
+		}
+		if printedOneofs[oneof.FullName()] {
+			continue
 		}
+		printedOneofs[oneof.FullName()] = true
+		lines = append(lines, [2]string{"  oneof " + string(oneof.Name()) + ":", "only one of the following args can be set"})
+		for j := 0; j < oneof.Fields().Len(); j++ {
+			member := oneof.Fields().Get(j)
+			lines = append(lines, [2]string{"    " + args.ArgName(member, naming), usageArgType(member)})
+		}
+	}
 
-		_, err := fmt.Fprintf(tw, "  %s\t%s\n", argSpecUsageLeftPart, argSpecUsageRightPart)
+	for _, line := range lines {
+		_, err := fmt.Fprintf(tw, "%s\t%s\n", line[0], line[1])
 		if err != nil {
 			CtxLogger(ctx).Errorf("cannot build usage arg for message %s: %s", message.FullName(), err)
 			return ""
@@ -97,6 +141,19 @@ func buildUsageArgs(ctx context.Context, message protoreflect.MessageDescriptor)
 	return paramsStr
 }
 
+// usageArgType returns the type of a field as displayed in the usage.
+func usageArgType(field protoreflect.FieldDescriptor) string {
+	argType := field.Kind().String()
+	// Well-known types are set using a single arg so we report them as scalars.
+	if field.Kind() == protoreflect.MessageKind && args.IsScalar(field) {
+		argType = string(field.Message().Name())
+	}
+	if enum := field.Enum(); enum != nil {
+		argType += "(" + strings.Join(args.EnumValueNames(enum), ", ") + ")"
+	}
+	return argType
+}
+
 const usageTemplate = `Usage:{{if .Runnable}}
   {{.UseLine}}{{end}}{{if .HasAvailableSubCommands}}
   {{.CommandPath}} [command]{{end}}{{if gt (len .Aliases) 0}}
@@ -124,56 +181,3 @@ Additional help topics:{{range .Commands}}{{if .IsAdditionalHelpTopicCommand}}
 
 Use "{{.CommandPath}} [command] --help" for more information about a command.{{end}}
 `
-
-func rpcRun(ctx context.Context, method protoreflect.MethodDescriptor) func(cmd *cobra.Command, args []string) error {
-	return func(cmd *cobra.Command, rawArgs []string) error {
-
-		// Create gRPC request and response message
-		req := dynamicpb.NewMessage(method.Input())
-		res := dynamicpb.NewMessage(method.Output())
-
-		// Unmarshal argument inside the gRPC request message
-		err := args.Unmarshal(rawArgs, req)
-		if err != nil {
-			return fmt.Errorf("cannot unmarshal args: %s", err)
-		}
-
-		// Get the gRPC connection from the context.
-		// Connection will be automatically closed in the Bootstrap method.
-		conn, err := CtxGrpcConnection(ctx)
-		if err != nil {
-			return fmt.Errorf("cannot get grpc connection: %s", err)
-		}
-
-		// Injecting metadata in outgoing context
-		ctx := metadata.NewOutgoingContext(ctx, CtxMD(ctx))
-
-		// Executing gRPC call
-		service := method.Parent().(protoreflect.ServiceDescriptor)
-		method := fmt.Sprintf("/%s/%s", service.FullName(), method.Name())
-		err = conn.Invoke(ctx, method, req, res)
-		if err != nil {
-			return fmt.Errorf("error while invoking rpc: %s", err)
-		}
-
-		// Marshal response in json
-		raw, err := protojson.MarshalOptions{
-			Multiline:       true,
-			Indent:          "  ",
-			UseProtoNames:   true,
-			EmitUnpopulated: true,
-		}.Marshal(res)
-
-		if err != nil {
-			return fmt.Errorf("cannont marshal response: %s", err)
-		}
-
-		// Write response on stdout
-		_, err = CtxStdout(ctx).Write(raw)
-		if err != nil {
-			return fmt.Errorf("cannont write response: %s", err)
-		}
-
-		return nil
-	}
-}
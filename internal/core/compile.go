@@ -0,0 +1,114 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// compileProto parses and links .proto files in-process.
+// Each path can either be a .proto file or a directory, in which case every .proto file it contains is loaded.
+// Files are resolved relative to the import paths, directories are used as import paths as well.
+func compileProto(paths []string, importPaths []string) (*descriptorpb.FileDescriptorSet, error) {
+	importPaths = append([]string(nil), importPaths...)
+
+	fileNames := []string(nil)
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("cannot open %s: %w", path, err)
+		}
+
+		if !info.IsDir() {
+			fileName, importPath := resolveProtoFileName(path, importPaths)
+			if importPath != "" {
+				importPaths = append(importPaths, importPath)
+			}
+			fileNames = append(fileNames, fileName)
+			continue
+		}
+
+		importPaths = append(importPaths, path)
+		err = filepath.Walk(path, func(filePath string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() || filepath.Ext(filePath) != ".proto" {
+				return nil
+			}
+			fileName, err := filepath.Rel(path, filePath)
+			if err != nil {
+				return err
+			}
+			fileNames = append(fileNames, filepath.ToSlash(fileName))
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("cannot list proto files in %s: %w", path, err)
+		}
+	}
+
+	parser := protoparse.Parser{
+		ImportPaths: importPaths,
+	}
+	fileDescs, err := parser.ParseFiles(fileNames...)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse proto files: %w", err)
+	}
+
+	// FileDescriptorSet must contain all the transitive dependencies.
+	fileDescSet := &descriptorpb.FileDescriptorSet{}
+	added := map[string]bool{}
+	var add func(fileDesc *desc.FileDescriptor)
+	add = func(fileDesc *desc.FileDescriptor) {
+		if added[fileDesc.GetName()] {
+			return
+		}
+		added[fileDesc.GetName()] = true
+		for _, dep := range fileDesc.GetDependencies() {
+			add(dep)
+		}
+		fileDescSet.File = append(fileDescSet.File, fileDesc.AsFileDescriptorProto())
+	}
+	for _, fileDesc := range fileDescs {
+		add(fileDesc)
+	}
+	return fileDescSet, nil
+}
+
+// resolveProtoFileName returns the name of a .proto file relative to the first import path that contains it.
+// If no import path contains the file, its directory is returned as an extra import path.
+func resolveProtoFileName(path string, importPaths []string) (fileName string, extraImportPath string) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		absPath = path
+	}
+
+	for _, importPath := range importPaths {
+		absImportPath, err := filepath.Abs(importPath)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(absImportPath, absPath)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		return filepath.ToSlash(rel), ""
+	}
+
+	return filepath.Base(path), filepath.Dir(path)
+}
+
+// isProtoSource returns true if a descriptor path references .proto sources instead of a descriptor set.
+func isProtoSource(path string) bool {
+	if filepath.Ext(path) == ".proto" {
+		return true
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
@@ -4,10 +4,13 @@ import (
 	"context"
 	"io"
 
+	"github.com/jerome-quere/grpc-cli/internal/args"
+	"github.com/jerome-quere/grpc-cli/internal/interpolate"
 	"github.com/sirupsen/logrus"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/reflect/protoregistry"
 )
 
 type contextDataKey struct{}
@@ -24,6 +27,21 @@ type contextData struct {
 	DialConfig *DialConfig
 	Connection *grpc.ClientConn
 	MD         metadata.MD
+
+	// Files holds the loaded descriptors. It is nil until descriptors are loaded.
+	Files *protoregistry.Files
+
+	// ArgNaming is the naming style of args in usage and autocompletion.
+	ArgNaming args.NamingStyle
+
+	// CacheDir is the directory where remote descriptors and call outputs are cached.
+	CacheDir string
+
+	// InterpolationData holds the values used to resolve placeholders in args.
+	InterpolationData interpolate.Data
+
+	// LastResponse is the JSON output of the last response printed by the current call.
+	LastResponse []byte
 }
 
 func ctxInjectData(ctx context.Context, data *contextData) context.Context {
@@ -42,6 +60,22 @@ func CtxStdout(ctx context.Context) io.Writer {
 	return ctxData(ctx).Stdout
 }
 
+func CtxStdin(ctx context.Context) io.Reader {
+	return ctxData(ctx).Stdin
+}
+
+func CtxArgNaming(ctx context.Context) args.NamingStyle {
+	return ctxData(ctx).ArgNaming
+}
+
+func CtxCacheDir(ctx context.Context) string {
+	return ctxData(ctx).CacheDir
+}
+
+func CtxInterpolationData(ctx context.Context) interpolate.Data {
+	return ctxData(ctx).InterpolationData
+}
+
 func CtxBinaryName(ctx context.Context) string {
 	return ctxData(ctx).BinaryName
 }
@@ -54,6 +88,10 @@ func CtxLogger(ctx context.Context) *logrus.Logger {
 	return ctxData(ctx).Logger
 }
 
+func CtxFiles(ctx context.Context) *protoregistry.Files {
+	return ctxData(ctx).Files
+}
+
 func CtxGrpcConnection(ctx context.Context) (*grpc.ClientConn, error) {
 	data := ctxData(ctx)
 	if data.Connection != nil {
@@ -61,6 +99,9 @@ func CtxGrpcConnection(ctx context.Context) (*grpc.ClientConn, error) {
 	}
 
 	conn, err := dial(ctx, data.DialConfig)
+	if err != nil {
+		return nil, withExitCode(ExitCodeDial, err)
+	}
 	data.Connection = conn
-	return data.Connection, err
+	return data.Connection, nil
 }
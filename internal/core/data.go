@@ -0,0 +1,169 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/jerome-quere/grpc-cli/internal/util"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gopkg.in/yaml.v3"
+)
+
+// readRequestData returns the request body passed with the --data flag.
+// The body can be given inline, read from a file using @path or from stdin using @-.
+func readRequestData(ctx context.Context, data string) ([]byte, error) {
+	switch {
+	case data == "@-":
+		stdin := CtxStdin(ctx)
+		if stdin == nil {
+			return nil, fmt.Errorf("stdin is not available")
+		}
+		raw, err := ioutil.ReadAll(stdin)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read request body from stdin: %w", err)
+		}
+		return raw, nil
+	case strings.HasPrefix(data, "@"):
+		raw, err := ioutil.ReadFile(util.ResolvePath(data[1:]))
+		if err != nil {
+			return nil, fmt.Errorf("cannot read request body file %s: %w", data[1:], err)
+		}
+		return raw, nil
+	default:
+		return []byte(data), nil
+	}
+}
+
+// unmarshalRequestData unmarshals a JSON or YAML request body into a message.
+// YAML bodies are converted to JSON so both formats use the protojson mapping.
+func unmarshalRequestData(ctx context.Context, raw []byte, dest protoreflect.Message) error {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return nil
+	}
+
+	if raw[0] != '{' {
+		var body yaml.Node
+		err := yaml.Unmarshal(raw, &body)
+		if err != nil {
+			return fmt.Errorf("cannot parse request body: %w", err)
+		}
+		value, err := yamlToJSON(&body)
+		if err != nil {
+			return fmt.Errorf("cannot parse request body: %w", err)
+		}
+		raw, err = json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("cannot convert request body to json: %w", err)
+		}
+	}
+
+	err := protojson.UnmarshalOptions{Resolver: newTypeResolver(CtxFiles(ctx))}.Unmarshal(raw, dest.Interface())
+	if err != nil {
+		return fmt.Errorf("cannot parse request body: %w", err)
+	}
+	return nil
+}
+
+// yamlToJSON converts a YAML node to a value that can be marshaled in JSON.
+// Scalars keep the text written by the user (e.g. a date stays the same string) so that
+// the protojson mapping applies to them, only numbers, booleans and null are typed.
+func yamlToJSON(node *yaml.Node) (interface{}, error) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		return yamlToJSON(node.Content[0])
+	case yaml.AliasNode:
+		return yamlToJSON(node.Alias)
+	case yaml.SequenceNode:
+		res := make([]interface{}, 0, len(node.Content))
+		for _, child := range node.Content {
+			value, err := yamlToJSON(child)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, value)
+		}
+		return res, nil
+	case yaml.MappingNode:
+		res := make(map[string]interface{}, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, child := node.Content[i], node.Content[i+1]
+			value, err := yamlToJSON(child)
+			if err != nil {
+				return nil, err
+			}
+			// Merge keys (<<: *anchor) add the entries of the merged mapping that are not already set.
+			if key.Tag == "!!merge" {
+				merged, isMap := value.(map[string]interface{})
+				if !isMap {
+					return nil, fmt.Errorf("line %d: cannot merge a non mapping value", key.Line)
+				}
+				for k, v := range merged {
+					if _, exist := res[k]; !exist {
+						res[k] = v
+					}
+				}
+				continue
+			}
+			// Map fields can have integer or boolean keys, they are written as is.
+			res[key.Value] = value
+		}
+		return res, nil
+	default:
+		return yamlScalarToJSON(node)
+	}
+}
+
+// yamlScalarToJSON converts a YAML scalar. Numbers that are not valid in JSON (e.g. 0x10, .inf) are decoded first.
+func yamlScalarToJSON(node *yaml.Node) (interface{}, error) {
+	switch node.ShortTag() {
+	case "!!null":
+		return nil, nil
+	case "!!bool":
+		var value bool
+		err := node.Decode(&value)
+		return value, err
+	case "!!int", "!!float":
+		if json.Valid([]byte(node.Value)) {
+			return json.Number(node.Value), nil
+		}
+		var value float64
+		err := node.Decode(&value)
+		switch {
+		case err != nil:
+			return nil, err
+		case math.IsNaN(value):
+			return "NaN", nil
+		case math.IsInf(value, 1):
+			return "Infinity", nil
+		case math.IsInf(value, -1):
+			return "-Infinity", nil
+		case node.ShortTag() == "!!int":
+			// Keep integers exact, float64 would round large values.
+			var value int64
+			if err := node.Decode(&value); err != nil {
+				var unsigned uint64
+				err = node.Decode(&unsigned)
+				return json.Number(strconv.FormatUint(unsigned, 10)), err
+			}
+			return json.Number(strconv.FormatInt(value, 10)), nil
+		default:
+			return value, nil
+		}
+	case "!!binary":
+		// protojson reads bytes from base64, only the line breaks of the YAML literal must be removed.
+		return strings.Join(strings.Fields(node.Value), ""), nil
+	default:
+		return node.Value, nil
+	}
+}
@@ -0,0 +1,157 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/jerome-quere/grpc-cli/internal/util"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// defaultCacheDir returns the directory where remote descriptors and call outputs are cached.
+// It is located next to the config file.
+func defaultCacheDir(configPath string) string {
+	return filepath.Join(filepath.Dir(util.ResolvePath(configPath)), "cache")
+}
+
+// descriptorCacheDir returns the directory where remote descriptors are cached.
+func descriptorCacheDir(cacheDir string) string {
+	return filepath.Join(cacheDir, "descriptors")
+}
+
+// loadDescriptors loads and merges multiple descriptors with the descriptors compiled from protoPaths.
+// Each descriptor can be a FileDescriptorSet file, a glob matching such files, an URL,
+// a .proto file or a directory of .proto files. protoPaths are compiled together (see compileProto).
+func loadDescriptors(ctx context.Context, descriptors []string, protoPaths []string, importPaths []string, cacheDir string) (*protoregistry.Files, error) {
+	fileDescSets := []*descriptorpb.FileDescriptorSet(nil)
+	if len(protoPaths) > 0 {
+		fileDescSet, err := compileProto(protoPaths, importPaths)
+		if err != nil {
+			return nil, err
+		}
+		fileDescSets = append(fileDescSets, fileDescSet)
+	}
+	for _, descriptor := range descriptors {
+		fileDescSet, err := loadDescriptorSet(ctx, descriptor, importPaths, cacheDir)
+		if err != nil {
+			return nil, err
+		}
+		fileDescSets = append(fileDescSets, fileDescSet...)
+	}
+
+	fileDescSet, err := mergeDescriptorSets(fileDescSets...)
+	if err != nil {
+		return nil, err
+	}
+	return newFiles(fileDescSet)
+}
+
+// loadDescriptorSet loads a single descriptor. A glob may return multiple sets, each match is loaded by its type.
+func loadDescriptorSet(ctx context.Context, descriptor string, importPaths []string, cacheDir string) ([]*descriptorpb.FileDescriptorSet, error) {
+	switch {
+	case isRemoteDescriptor(descriptor):
+		fileDescSet, err := fetchDescriptorSet(ctx, descriptor, cacheDir)
+		return []*descriptorpb.FileDescriptorSet{fileDescSet}, err
+
+	case strings.ContainsAny(descriptor, "*?["):
+		paths, err := filepath.Glob(descriptor)
+		if err != nil {
+			return nil, fmt.Errorf("invalid descriptor pattern %s: %w", descriptor, err)
+		}
+		if len(paths) == 0 {
+			return nil, fmt.Errorf("no descriptor file matches %s", descriptor)
+		}
+
+		fileDescSets := []*descriptorpb.FileDescriptorSet(nil)
+		protoPaths := []string(nil)
+		for _, path := range paths {
+			// Matching .proto files are compiled together so they can import each other.
+			if isProtoSource(path) {
+				protoPaths = append(protoPaths, path)
+				continue
+			}
+			fileDescSet, err := readDescriptorSet(path)
+			if err != nil {
+				return nil, err
+			}
+			fileDescSets = append(fileDescSets, fileDescSet)
+		}
+		if len(protoPaths) > 0 {
+			fileDescSet, err := compileProto(protoPaths, importPaths)
+			if err != nil {
+				return nil, err
+			}
+			fileDescSets = append(fileDescSets, fileDescSet)
+		}
+		return fileDescSets, nil
+
+	case isProtoSource(descriptor):
+		fileDescSet, err := compileProto([]string{descriptor}, importPaths)
+		return []*descriptorpb.FileDescriptorSet{fileDescSet}, err
+
+	default:
+		fileDescSet, err := readDescriptorSet(descriptor)
+		return []*descriptorpb.FileDescriptorSet{fileDescSet}, err
+	}
+}
+
+// mergeDescriptorSets merges multiple FileDescriptorSet into a single one.
+// Identical files defined in multiple sets are only kept once.
+// An error is returned if two sets define the same file with different contents.
+func mergeDescriptorSets(fileDescSets ...*descriptorpb.FileDescriptorSet) (*descriptorpb.FileDescriptorSet, error) {
+	merged := &descriptorpb.FileDescriptorSet{}
+	files := map[string]*descriptorpb.FileDescriptorProto{}
+
+	for _, fileDescSet := range fileDescSets {
+		for _, file := range fileDescSet.GetFile() {
+			existing, exist := files[file.GetName()]
+			switch {
+			case !exist:
+				files[file.GetName()] = file
+				merged.File = append(merged.File, file)
+			case !proto.Equal(existing, file):
+				return nil, fmt.Errorf("file %s is defined multiple times with different contents", file.GetName())
+			}
+		}
+	}
+	return merged, nil
+}
+
+func readDescriptorSet(path string) (*descriptorpb.FileDescriptorSet, error) {
+	descriptorRaw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannont open file %s: %w", path, err)
+	}
+	return parseDescriptorSet(descriptorRaw)
+}
+
+func parseDescriptorSet(descriptorRaw []byte) (*descriptorpb.FileDescriptorSet, error) {
+	fileDescSet := &descriptorpb.FileDescriptorSet{}
+	err := proto.Unmarshal(descriptorRaw, fileDescSet)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse descriptor set: %w", err)
+	}
+	return fileDescSet, nil
+}
+
+func loadDescriptorFromBytes(descriptorRaw []byte) (*protoregistry.Files, error) {
+	fileDescSet, err := parseDescriptorSet(descriptorRaw)
+	if err != nil {
+		return nil, err
+	}
+	return newFiles(fileDescSet)
+}
+
+func newFiles(fileDescSet *descriptorpb.FileDescriptorSet) (*protoregistry.Files, error) {
+	files, err := protodesc.NewFiles(fileDescSet)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create proto reflect struct: %w", err)
+	}
+	return files, nil
+}
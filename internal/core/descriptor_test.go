@@ -0,0 +1,59 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func Test_mergeDescriptorSets(t *testing.T) {
+	fileDescSet, err := parseDescriptorSet(rawProto)
+	require.NoError(t, err)
+
+	t.Run("identical files", func(t *testing.T) {
+		merged, err := mergeDescriptorSets(fileDescSet, proto.Clone(fileDescSet).(*descriptorpb.FileDescriptorSet))
+		require.NoError(t, err)
+		assert.Len(t, merged.File, len(fileDescSet.File))
+
+		_, err = newFiles(merged)
+		require.NoError(t, err)
+	})
+
+	t.Run("conflicting files", func(t *testing.T) {
+		conflicting := proto.Clone(fileDescSet).(*descriptorpb.FileDescriptorSet)
+		for _, file := range conflicting.File {
+			if file.GetName() == "test/test.proto" {
+				file.MessageType = append(file.MessageType, &descriptorpb.DescriptorProto{Name: proto.String("Other")})
+			}
+		}
+
+		_, err := mergeDescriptorSets(fileDescSet, conflicting)
+		assert.EqualError(t, err, "file test/test.proto is defined multiple times with different contents")
+	})
+}
+
+func TestBootstrap_descriptors(t *testing.T) {
+
+	t.Run("multiple descriptors", Test(&TestConfig{
+		Cmd:   "grpc-cli --descriptor testdata/test.pb --descriptor ../../protobuf/test/test.proto -I ../../protobuf rpc test.Api -h",
+		Check: TestCheckGolden(),
+	}))
+
+	t.Run("glob", Test(&TestConfig{
+		Cmd:   "grpc-cli --descriptor testdata/*.pb rpc test.Api -h",
+		Check: TestCheckGolden(),
+	}))
+
+	t.Run("proto glob", Test(&TestConfig{
+		Cmd:   "grpc-cli --descriptor testdata/proto/*.proto --descriptor testdata/test.pb rpc -h",
+		Check: TestCheckCombine(TestCheckExitCode(ExitCodeSuccess), TestCheckGolden()),
+	}))
+
+	t.Run("glob no match", Test(&TestConfig{
+		Cmd:   "grpc-cli --descriptor testdata/*.unknown rpc test.Api -h",
+		Check: TestCheckGolden(),
+	}))
+}
@@ -0,0 +1,57 @@
+package core
+
+import "errors"
+
+// Exit codes returned by Bootstrap.
+// When an rpc fails, the exit code is ExitCodeRpcOffset + the gRPC status code (e.g. 69 for NOT_FOUND).
+const (
+	ExitCodeSuccess       = 0
+	ExitCodeError         = 1 // Unexpected error
+	ExitCodeInvalidArgs   = 2 // Invalid flags, command or args
+	ExitCodeInvalidConfig = 3 // Config file or profile cannot be loaded
+	ExitCodeDescriptor    = 4 // Descriptors cannot be loaded
+	ExitCodeDial          = 5 // Connection to the target cannot be established
+	ExitCodeRpcOffset     = 64
+)
+
+// exitCodeError associates an exit code to an error.
+type exitCodeError struct {
+	code int
+	err  error
+}
+
+func (e *exitCodeError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitCodeError) Unwrap() error {
+	return e.err
+}
+
+// withExitCode associates an exit code to err.
+// If err already wraps an exitCodeError it is returned unchanged so the most specific exit code wins.
+func withExitCode(code int, err error) error {
+	var exitErr *exitCodeError
+	if err == nil || errors.As(err, &exitErr) {
+		return err
+	}
+	return &exitCodeError{code: code, err: err}
+}
+
+// exitCodeFromError returns the exit code associated with err.
+func exitCodeFromError(err error) int {
+	if err == nil {
+		return ExitCodeSuccess
+	}
+
+	var exitErr *exitCodeError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+
+	if st, isStatus := statusFromError(err); isStatus {
+		return ExitCodeRpcOffset + int(st.Code())
+	}
+
+	return ExitCodeError
+}
@@ -1,6 +1,8 @@
 package core
 
 import (
+	"strings"
+
 	"github.com/jerome-quere/grpc-cli/internal/config"
 	"github.com/spf13/pflag"
 )
@@ -8,16 +10,20 @@ import (
 type FlagSet struct {
 	*pflag.FlagSet
 
-	Config     string
-	Profile    string
-	Descriptor string
-	Target     string
-	Metadata   MetadataFlags
-	CaCert     string
-	Cert       string
-	Key        string
-	Verbose    bool
-	DisableTLS bool
+	Config      string
+	Profile     string
+	Descriptors []string
+	Proto       []string
+	ImportPaths []string
+	Target      string
+	Metadata    MetadataFlags
+	CaCert      string
+	Cert        string
+	Key         string
+	Verbose     bool
+	DisableTLS  bool
+	ArgNaming   string
+	Vars        []string
 }
 
 func NewFlagSet(binaryName string) *FlagSet {
@@ -27,7 +33,9 @@ func NewFlagSet(binaryName string) *FlagSet {
 
 	flags.StringVarP(&flags.Config, "config", "c", config.DefaultConfigPath, "Path to the config file")
 	flags.StringVarP(&flags.Profile, "profile", "p", config.DefaultProfileName, "Config profile to load")
-	flags.StringVarP(&flags.Descriptor, "descriptor", "d", "", "Path to the descriptor file")
+	flags.StringArrayVarP(&flags.Descriptors, "descriptor", "", nil, "Path, glob or URL of a descriptor file, a .proto file, a directory of .proto files or \"reflection\" to use gRPC server reflection (default when not set). Can be repeated")
+	flags.StringArrayVarP(&flags.Proto, "proto", "", nil, "Path to a .proto file or a directory of .proto files to compile")
+	flags.StringArrayVarP(&flags.ImportPaths, "import-path", "I", nil, "Directory used to resolve .proto imports")
 	flags.StringVarP(&flags.Target, "target", "t", "", "The grpc connection target")
 	flags.StringVarP(&flags.CaCert, "ca-cert", "", "", "Root CA you want to use. Use system by default")
 	flags.StringVarP(&flags.Cert, "cert", "", "", "Client certificate path. (PEM format)")
@@ -35,6 +43,8 @@ func NewFlagSet(binaryName string) *FlagSet {
 	flags.BoolVarP(&flags.Verbose, "verbose", "v", false, "Enable verbose")
 	flags.BoolVarP(&flags.DisableTLS, "disable-tls", "", false, "Enable verbose")
 	flags.VarP(&flags.Metadata, "metadata", "m", "Metadata to attache to the request")
+	flags.StringArrayVarP(&flags.Vars, "var", "", nil, "Variable used in ${NAME} placeholders, as NAME=value. Can be repeated")
+	flags.StringVarP(&flags.ArgNaming, "arg-naming", "", "", "Naming style of args in usage and autocompletion: proto (default) or json")
 
 	flags.ParseErrorsWhitelist.UnknownFlags = true
 	flags.Usage = func() {}
@@ -45,8 +55,14 @@ func (fs *FlagSet) GetProfile() config.Profile {
 	profile := config.Profile{
 		Metadata: fs.Metadata.MD(),
 	}
-	if fs.Descriptor != "" {
-		profile.Descriptor = &fs.Descriptor
+	if len(fs.Descriptors) > 0 {
+		profile.Descriptors = fs.Descriptors
+	}
+	if len(fs.Proto) > 0 {
+		profile.Proto = fs.Proto
+	}
+	if len(fs.ImportPaths) > 0 {
+		profile.ImportPaths = fs.ImportPaths
 	}
 	if fs.Target != "" {
 		profile.Target = &fs.Target
@@ -63,6 +79,19 @@ func (fs *FlagSet) GetProfile() config.Profile {
 	if fs.DisableTLS {
 		profile.DisableTLS = &fs.DisableTLS
 	}
+	if fs.ArgNaming != "" {
+		profile.ArgNaming = &fs.ArgNaming
+	}
+	if len(fs.Vars) > 0 {
+		profile.Vars = map[string]string{}
+		for _, v := range fs.Vars {
+			tmp := strings.SplitN(v, "=", 2)
+			if len(tmp) < 2 {
+				tmp = append(tmp, "")
+			}
+			profile.Vars[tmp[0]] = tmp[1]
+		}
+	}
 
 	return profile
 }
@@ -0,0 +1,170 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jerome-quere/grpc-cli/internal/args"
+	"github.com/jerome-quere/grpc-cli/internal/util"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// maxRequestLineSize is the maximum size of a single request message line.
+const maxRequestLineSize = 4 * 1024 * 1024
+
+// requestReader reads newline-delimited request messages.
+// Each line is either a JSON object or a list of key=value args split like shell words (see splitShellWords).
+// Args are interpolated like the args of the command line. Empty lines are ignored.
+type requestReader struct {
+	ctx      context.Context
+	closer   io.Closer
+	scanner  *bufio.Scanner
+	desc     protoreflect.MessageDescriptor
+	resolver *typeResolver
+	line     int
+}
+
+// newRequestReader creates a requestReader that read messages from the given file path.
+// If path is empty or "-" messages are read from stdin.
+func newRequestReader(ctx context.Context, path string, desc protoreflect.MessageDescriptor) (*requestReader, error) {
+	var input io.Reader
+	var closer io.Closer
+
+	if path == "" || path == "-" {
+		input = CtxStdin(ctx)
+		if input == nil {
+			return nil, fmt.Errorf("stdin is not available")
+		}
+	} else {
+		f, err := os.Open(util.ResolvePath(path))
+		if err != nil {
+			return nil, fmt.Errorf("cannot open input file %s: %s", path, err)
+		}
+		input = f
+		closer = f
+	}
+
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(nil, maxRequestLineSize)
+
+	return &requestReader{
+		ctx:      ctx,
+		closer:   closer,
+		scanner:  scanner,
+		desc:     desc,
+		resolver: newTypeResolver(CtxFiles(ctx)),
+	}, nil
+}
+
+// Next returns the next request message or io.EOF when there is no more message.
+func (r *requestReader) Next() (*dynamicpb.Message, error) {
+	for r.scanner.Scan() {
+		r.line++
+		line := bytes.TrimSpace(r.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		msg := dynamicpb.NewMessage(r.desc)
+		if line[0] == '{' {
+			err := protojson.UnmarshalOptions{Resolver: r.resolver}.Unmarshal(line, msg)
+			if err != nil {
+				return nil, withExitCode(ExitCodeInvalidArgs, fmt.Errorf("cannot parse request on line %d: %s", r.line, err))
+			}
+			return msg, nil
+		}
+
+		rawArgs, err := splitShellWords(string(line))
+		if err != nil {
+			return nil, withExitCode(ExitCodeInvalidArgs, fmt.Errorf("cannot parse args on line %d: %s", r.line, err))
+		}
+		rawArgs, err = interpolateArgs(r.ctx, rawArgs)
+		if err != nil {
+			return nil, withExitCode(ExitCodeInvalidArgs, fmt.Errorf("cannot interpolate args on line %d: %s", r.line, err))
+		}
+		err = args.UnmarshalOptions{Resolver: r.resolver}.Unmarshal(rawArgs, msg)
+		if err != nil {
+			return nil, withExitCode(ExitCodeInvalidArgs, fmt.Errorf("cannot unmarshal args on line %d: %s", r.line, err))
+		}
+		return msg, nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read request: %s", err)
+	}
+	return nil, io.EOF
+}
+
+// splitShellWords splits a line into words the way a POSIX shell does, without any expansion.
+// Words are separated by spaces, single quotes keep their content as is, double quotes and backslashes
+// escape spaces and quotes (e.g. str='hello world' or str="it's").
+func splitShellWords(line string) ([]string, error) {
+	words := []string(nil)
+	word := strings.Builder{}
+	inWord := false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == ' ' || c == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+
+		case c == '\\':
+			if i+1 == len(line) {
+				return nil, fmt.Errorf("trailing backslash")
+			}
+			i++
+			word.WriteByte(line[i])
+			inWord = true
+
+		case c == '\'':
+			end := strings.IndexByte(line[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated single quote")
+			}
+			word.WriteString(line[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+
+		case c == '"':
+			i++
+			for ; i < len(line) && line[i] != '"'; i++ {
+				// Same as a shell: in double quotes, backslashes only escape these characters.
+				if line[i] == '\\' && i+1 < len(line) && strings.IndexByte("\"\\$`", line[i+1]) >= 0 {
+					i++
+				}
+				word.WriteByte(line[i])
+			}
+			if i == len(line) {
+				return nil, fmt.Errorf("unterminated double quote")
+			}
+			inWord = true
+
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// Close releases the underlying file if any. Stdin is never closed.
+func (r *requestReader) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}
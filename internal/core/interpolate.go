@@ -0,0 +1,109 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/jerome-quere/grpc-cli/internal/config"
+	"github.com/jerome-quere/grpc-cli/internal/interpolate"
+	"github.com/sirupsen/logrus"
+)
+
+// buildInterpolationData returns the values used to resolve placeholders.
+// Profile vars can themselves use environment variables (e.g. token: ${API_TOKEN}).
+// An unreadable last response only disables ${last.field} so that commands not using it still work.
+func buildInterpolationData(logger *logrus.Logger, profile config.Profile, cacheDir string) (interpolate.Data, error) {
+	envData := interpolate.NewData(nil, nil)
+	vars := make(map[string]string, len(profile.Vars))
+	for name, value := range profile.Vars {
+		value, err := interpolate.Interpolate(value, envData)
+		if err != nil {
+			return interpolate.Data{}, fmt.Errorf("cannot interpolate var %s: %w", name, err)
+		}
+		vars[name] = value
+	}
+
+	last, err := loadLastResponse(cacheDir)
+	if err != nil {
+		logger.Warnf("cannot load last response from %s: %s", lastResponsePath(cacheDir), err)
+		last = nil
+	}
+	return interpolate.NewData(vars, last), nil
+}
+
+// lastResponsePath returns the file where the output of the last call is saved.
+func lastResponsePath(cacheDir string) string {
+	return filepath.Join(cacheDir, "last-response.json")
+}
+
+// loadLastResponse returns the output of the last call decoded from JSON, or nil if no call was made yet.
+// Numbers are kept as json.Number so large integers are not rounded.
+func loadLastResponse(cacheDir string) (interface{}, error) {
+	raw, err := ioutil.ReadFile(lastResponsePath(cacheDir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var last interface{}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	err = decoder.Decode(&last)
+	if err != nil {
+		return nil, err
+	}
+	return last, nil
+}
+
+// saveLastResponse saves the output of a call so that the next calls can use it with ${last.field}.
+// Failing to save it does not fail the call.
+func saveLastResponse(ctx context.Context, raw []byte) {
+	path := lastResponsePath(CtxCacheDir(ctx))
+	err := writeFileAtomic(path, raw)
+	if err != nil {
+		CtxLogger(ctx).Warnf("cannot save response to %s: %s", path, err)
+	}
+}
+
+// writeFileAtomic writes a file through a temporary file renamed into place,
+// so that concurrent calls never read a partially written file.
+func writeFileAtomic(path string, raw []byte) error {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(raw)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// interpolateArgs replaces the placeholders of every arg.
+func interpolateArgs(ctx context.Context, rawArgs []string) ([]string, error) {
+	res := make([]string, 0, len(rawArgs))
+	for _, arg := range rawArgs {
+		arg, err := interpolate.Interpolate(arg, CtxInterpolationData(ctx))
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, arg)
+	}
+	return res, nil
+}
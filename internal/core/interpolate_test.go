@@ -0,0 +1,53 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jerome-quere/grpc-cli/internal/args"
+	"github.com/jerome-quere/grpc-cli/internal/interpolate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Test_interpolateArgs_roundTrip checks that the args printed by the args command are parsed back to the same request
+// by the rpc command, even when values look like placeholders.
+func Test_interpolateArgs_roundTrip(t *testing.T) {
+	fileDescSet := descriptorpb.FileDescriptorSet{}
+	require.NoError(t, proto.Unmarshal(rawProto, &fileDescSet))
+	files, err := protodesc.NewFiles(&fileDescSet)
+	require.NoError(t, err)
+	desc, err := files.FindDescriptorByName("test.Simple")
+	require.NoError(t, err)
+	simple := desc.(protoreflect.MessageDescriptor)
+
+	ctx := ctxInjectData(context.Background(), &contextData{
+		InterpolationData: interpolate.Data{Env: map[string]string{"HOME": "/home/test"}, Vars: map[string]string{"x": "y"}},
+	})
+
+	for _, body := range []string{
+		`{"str": "${HOME}"}`,
+		`{"str": "$${HOME}", "strs": ["{{ .vars.x }}", "{{{", "${{", "$"]}`,
+		`{"labels": {"${HOME}": "{{ .env.HOME }}", "a.b": "${x}"}}`,
+	} {
+		req := dynamicpb.NewMessage(simple)
+		require.NoError(t, protojson.Unmarshal([]byte(body), req))
+
+		reqArgs := args.Marshal(req)
+		for i, arg := range reqArgs {
+			reqArgs[i] = interpolate.Escape(arg)
+		}
+		reqArgs, err := interpolateArgs(ctx, reqArgs)
+		require.NoError(t, err, body)
+
+		res := dynamicpb.NewMessage(simple)
+		require.NoError(t, args.Unmarshal(reqArgs, res), body)
+		assert.True(t, proto.Equal(req, res), "%s: got %v", body, res)
+	}
+}
@@ -0,0 +1,227 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// v1 and v1alpha reflection services share the exact same messages, only the package differs.
+// This allows us to use v1alpha generated messages to talk to both versions.
+const (
+	reflectionV1Method      = "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo"
+	reflectionV1AlphaMethod = "/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo"
+)
+
+// helpReflectionTimeout bounds the reflection round-trip of help and autocompletion when no cached descriptor exists.
+const helpReflectionTimeout = 2 * time.Second
+
+// loadDescriptorFromReflection fetches all file descriptors exposed by the target using gRPC server reflection.
+// v1 reflection service is used when available, v1alpha otherwise.
+// Fetched descriptors are cached per target in cacheDir. Help and autocompletion requests use the cached descriptors
+// and never fail: if there is no cache and the target cannot be reached quickly, no service is listed.
+func loadDescriptorFromReflection(ctx context.Context, cacheDir string, isHelp bool) (*protoregistry.Files, error) {
+	key := sha256.Sum256([]byte(ctxData(ctx).DialConfig.Target))
+	cachePath := filepath.Join(cacheDir, "reflection-"+hex.EncodeToString(key[:])+".pb")
+
+	if isHelp {
+		if fileDescSet, err := readDescriptorSet(cachePath); err == nil {
+			CtxLogger(ctx).Debugf("Using cached reflection descriptors from %s", cachePath)
+			return newFiles(fileDescSet)
+		}
+
+		helpCtx, cancel := context.WithTimeout(ctx, helpReflectionTimeout)
+		defer cancel()
+		fileDescSet, err := fetchReflectionDescriptorSet(helpCtx)
+		if err != nil {
+			CtxLogger(ctx).Warnf("cannot load descriptors using reflection, services are not listed: %s", err)
+			return new(protoregistry.Files), nil
+		}
+		saveReflectionDescriptorSet(ctx, cachePath, fileDescSet)
+		return newFiles(fileDescSet)
+	}
+
+	fileDescSet, err := fetchReflectionDescriptorSet(ctx)
+	if err != nil {
+		return nil, err
+	}
+	saveReflectionDescriptorSet(ctx, cachePath, fileDescSet)
+	return newFiles(fileDescSet)
+}
+
+// fetchReflectionDescriptorSet fetches the descriptors of the target, trying v1 reflection first.
+func fetchReflectionDescriptorSet(ctx context.Context) (*descriptorpb.FileDescriptorSet, error) {
+	conn, err := CtxGrpcConnection(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get grpc connection: %w", err)
+	}
+
+	ctx = metadata.NewOutgoingContext(ctx, CtxMD(ctx))
+
+	fileDescSet, err := fetchReflectionFiles(ctx, conn, reflectionV1Method)
+	if status.Code(err) == codes.Unimplemented {
+		CtxLogger(ctx).Debugf("Reflection v1 is not implemented, falling back to v1alpha")
+		fileDescSet, err = fetchReflectionFiles(ctx, conn, reflectionV1AlphaMethod)
+	}
+	return fileDescSet, err
+}
+
+// saveReflectionDescriptorSet caches fetched descriptors. Failing to cache them does not fail the command.
+func saveReflectionDescriptorSet(ctx context.Context, cachePath string, fileDescSet *descriptorpb.FileDescriptorSet) {
+	raw, err := proto.Marshal(fileDescSet)
+	if err == nil {
+		err = writeFileAtomic(cachePath, raw)
+	}
+	if err != nil {
+		CtxLogger(ctx).Warnf("cannot cache reflection descriptors: %s", err)
+	}
+}
+
+// fetchReflectionFiles lists all services exposed by the server and fetches the files
+// that define them along with all their transitive dependencies.
+func fetchReflectionFiles(ctx context.Context, conn *grpc.ClientConn, method string) (*descriptorpb.FileDescriptorSet, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ClientStreams: true, ServerStreams: true}, method)
+	if err != nil {
+		return nil, err
+	}
+	client := &reflectionClient{
+		stream: stream,
+		files:  map[string]*descriptorpb.FileDescriptorProto{},
+	}
+
+	services, err := client.listServices()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, service := range services {
+		// We don't want reflection services to be part of the command tree.
+		if strings.HasPrefix(service, "grpc.reflection.") {
+			continue
+		}
+		err := client.fetch(&rpb.ServerReflectionRequest{
+			MessageRequest: &rpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: service},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("cannot fetch descriptor for service %s: %w", service, err)
+		}
+	}
+
+	// Servers may not send all transitive dependencies so we ask for the missing ones.
+	for missing := client.missingDependencies(); len(missing) > 0; missing = client.missingDependencies() {
+		for _, fileName := range missing {
+			err := client.fetch(&rpb.ServerReflectionRequest{
+				MessageRequest: &rpb.ServerReflectionRequest_FileByFilename{FileByFilename: fileName},
+			})
+			if err != nil {
+				return nil, fmt.Errorf("cannot fetch descriptor for file %s: %w", fileName, err)
+			}
+			if client.files[fileName] == nil {
+				return nil, fmt.Errorf("server did not return descriptor for file %s", fileName)
+			}
+		}
+	}
+
+	err = stream.CloseSend()
+	if err != nil {
+		return nil, err
+	}
+
+	fileDescSet := &descriptorpb.FileDescriptorSet{}
+	for _, fileName := range client.order {
+		fileDescSet.File = append(fileDescSet.File, client.files[fileName])
+	}
+	return fileDescSet, nil
+}
+
+// reflectionClient sends reflection requests on a single stream and keeps track of received files.
+type reflectionClient struct {
+	stream grpc.ClientStream
+	files  map[string]*descriptorpb.FileDescriptorProto
+	order  []string
+}
+
+func (c *reflectionClient) roundTrip(req *rpb.ServerReflectionRequest) (*rpb.ServerReflectionResponse, error) {
+	err := c.stream.SendMsg(req)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &rpb.ServerReflectionResponse{}
+	err = c.stream.RecvMsg(res)
+	if err != nil {
+		return nil, err
+	}
+
+	if errRes := res.GetErrorResponse(); errRes != nil {
+		return nil, status.Error(codes.Code(errRes.GetErrorCode()), errRes.GetErrorMessage())
+	}
+	return res, nil
+}
+
+func (c *reflectionClient) listServices() ([]string, error) {
+	res, err := c.roundTrip(&rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_ListServices{},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	services := []string(nil)
+	for _, service := range res.GetListServicesResponse().GetService() {
+		services = append(services, service.GetName())
+	}
+	return services, nil
+}
+
+// fetch sends a file request and stores every returned file.
+func (c *reflectionClient) fetch(req *rpb.ServerReflectionRequest) error {
+	res, err := c.roundTrip(req)
+	if err != nil {
+		return err
+	}
+
+	for _, raw := range res.GetFileDescriptorResponse().GetFileDescriptorProto() {
+		file := &descriptorpb.FileDescriptorProto{}
+		err := proto.Unmarshal(raw, file)
+		if err != nil {
+			return fmt.Errorf("cannot parse file descriptor: %w", err)
+		}
+		if _, exist := c.files[file.GetName()]; !exist {
+			c.files[file.GetName()] = file
+			c.order = append(c.order, file.GetName())
+		}
+	}
+	return nil
+}
+
+// missingDependencies returns files that are imported but were not received yet.
+func (c *reflectionClient) missingDependencies() []string {
+	missing := []string(nil)
+	seen := map[string]bool{}
+	for _, fileName := range c.order {
+		for _, dep := range c.files[fileName].GetDependency() {
+			if _, exist := c.files[dep]; !exist && !seen[dep] {
+				seen[dep] = true
+				missing = append(missing, dep)
+			}
+		}
+	}
+	return missing
+}
@@ -0,0 +1,110 @@
+package core
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	_ "google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+var registerTestFileOnce sync.Once
+
+// registerTestReflection registers the test.Api service along with the reflection service.
+func registerTestReflection(t *testing.T) func(server *grpc.Server) {
+	// Reflection service looks up files in the global registry.
+	registerTestFileOnce.Do(func() {
+		fileDescSet := descriptorpb.FileDescriptorSet{}
+		require.NoError(t, proto.Unmarshal(rawProto, &fileDescSet))
+		for _, file := range fileDescSet.File {
+			if file.GetName() != "test/test.proto" {
+				continue
+			}
+			fd, err := protodesc.NewFile(file, protoregistry.GlobalFiles)
+			require.NoError(t, err)
+			require.NoError(t, protoregistry.GlobalFiles.RegisterFile(fd))
+		}
+	})
+
+	registerApi := registerTestApi(t)
+	return func(server *grpc.Server) {
+		registerApi(server)
+		reflection.Register(server)
+	}
+}
+
+func TestBootstrap_reflection(t *testing.T) {
+
+	t.Run("usage", Test(&TestConfig{
+		Server: registerTestReflection(t),
+		Cmd:    "grpc-cli --descriptor reflection rpc test.Api -h",
+		Check:  TestCheckGolden(),
+	}))
+
+	t.Run("rpc", Test(&TestConfig{
+		Server: registerTestReflection(t),
+		Cmd:    "grpc-cli --descriptor reflection rpc test.Api Echo str=abc wrapper_str=def",
+		Check:  TestCheckGolden(),
+	}))
+
+	t.Run("no reflection service", Test(&TestConfig{
+		Server: registerTestApi(t),
+		Cmd:    "grpc-cli --descriptor reflection rpc test.Api -h",
+		Check:  TestCheckCombine(TestCheckExitCode(ExitCodeSuccess), TestCheckGolden()),
+	}))
+
+	t.Run("rpc without reflection service", Test(&TestConfig{
+		Server: registerTestApi(t),
+		Cmd:    "grpc-cli --descriptor reflection rpc test.Api Echo str=abc",
+		Check:  TestCheckCombine(TestCheckExitCode(ExitCodeDescriptor), TestCheckGolden()),
+	}))
+
+	t.Run("unreachable target usage", Test(&TestConfig{
+		Cmd:   "grpc-cli --descriptor reflection --target 127.0.0.1:1 rpc -h",
+		Check: TestCheckExitCode(ExitCodeSuccess),
+	}))
+
+	t.Run("cache", func(t *testing.T) {
+		cacheDir := t.TempDir()
+		Test(&TestConfig{
+			Server:   registerTestReflection(t),
+			CacheDir: cacheDir,
+			Cmd:      "grpc-cli --descriptor reflection rpc test.Api Echo str=abc",
+			Check:    TestCheckExitCode(ExitCodeSuccess),
+		})(t)
+
+		paths, err := filepath.Glob(filepath.Join(descriptorCacheDir(cacheDir), "reflection-*.pb"))
+		require.NoError(t, err)
+		require.Len(t, paths, 1)
+		_, err = readDescriptorSet(paths[0])
+		require.NoError(t, err)
+	})
+}
+
+func Test_isHelpRequest(t *testing.T) {
+	for cmd, expected := range map[string]bool{
+		"grpc-cli":                              true,
+		"grpc-cli -h":                           true,
+		"grpc-cli -t localhost:1 rpc":           true,
+		"grpc-cli rpc test.Api":                 true,
+		"grpc-cli rpc test.Api Echo --help":     true,
+		"grpc-cli autocomplete complete bash 1": true,
+		"grpc-cli rpc test.Api Echo str=a":      false,
+		"grpc-cli rpc test.Api Echo -- -h":      false,
+		"grpc-cli args test.Api Echo":           false,
+	} {
+		rawArgs := strings.Split(cmd, " ")
+		flags := NewFlagSet(rawArgs[0])
+		_ = flags.Parse(rawArgs)
+		assert.Equal(t, expected, isHelpRequest(rawArgs, flags.Args()), cmd)
+	}
+}
@@ -0,0 +1,156 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// remoteDescriptorCacheEntry holds information required to revalidate a cached descriptor.
+type remoteDescriptorCacheEntry struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// isRemoteDescriptor returns true if the descriptor must be loaded from an URL.
+func isRemoteDescriptor(descriptor string) bool {
+	for _, scheme := range []string{"http://", "https://", "file://"} {
+		if strings.HasPrefix(descriptor, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchDescriptorSet loads a FileDescriptorSet from a file:// or http(s):// URL.
+// Remote descriptors are cached in cacheDir and revalidated using ETag and Last-Modified headers.
+// If the descriptor cannot be downloaded the cached copy is used.
+func fetchDescriptorSet(ctx context.Context, rawURL string, cacheDir string) (*descriptorpb.FileDescriptorSet, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid descriptor url %s: %w", rawURL, err)
+	}
+
+	// Only absolute URLs are supported (file:///path), a relative path would be parsed as a host (file://dir/file.pb).
+	if u.Scheme == "file" {
+		if u.Host != "" && u.Host != "localhost" {
+			return nil, fmt.Errorf("invalid descriptor url %s: file urls must be absolute, e.g. file:///path/to/descriptor.pb", rawURL)
+		}
+		return readDescriptorSet(u.Path)
+	}
+
+	key := sha256.Sum256([]byte(rawURL))
+	cachePath := filepath.Join(cacheDir, hex.EncodeToString(key[:]))
+	cachedRaw, cacheEntry := readRemoteDescriptorCache(cachePath)
+
+	raw, newCacheEntry, err := downloadDescriptor(ctx, rawURL, cachedRaw != nil, cacheEntry)
+	switch {
+	case err != nil && cachedRaw != nil:
+		CtxLogger(ctx).Warnf("cannot download descriptor, using cached copy: %s", err)
+		return parseDescriptorSet(cachedRaw)
+	case err != nil:
+		return nil, err
+	case raw == nil:
+		CtxLogger(ctx).Debugf("Cached descriptor is up to date")
+		return parseDescriptorSet(cachedRaw)
+	}
+
+	// Make sure the descriptor is valid before caching it.
+	fileDescSet, err := parseDescriptorSet(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writeRemoteDescriptorCache(cachePath, raw, newCacheEntry)
+	if err != nil {
+		CtxLogger(ctx).Warnf("cannot cache descriptor: %s", err)
+	}
+	return fileDescSet, nil
+}
+
+// downloadDescriptor downloads a descriptor. A nil content with no error means the cached copy is still valid.
+func downloadDescriptor(ctx context.Context, rawURL string, hasCache bool, cacheEntry remoteDescriptorCacheEntry) ([]byte, remoteDescriptorCacheEntry, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, cacheEntry, fmt.Errorf("cannot create request: %w", err)
+	}
+	if hasCache && cacheEntry.ETag != "" {
+		req.Header.Set("If-None-Match", cacheEntry.ETag)
+	}
+	if hasCache && cacheEntry.LastModified != "" {
+		req.Header.Set("If-Modified-Since", cacheEntry.LastModified)
+	}
+
+	client := &http.Client{Timeout: defaultDialTimeout}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, cacheEntry, fmt.Errorf("cannot download %s: %w", rawURL, err)
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusNotModified && hasCache:
+		return nil, cacheEntry, nil
+	case res.StatusCode != http.StatusOK:
+		return nil, cacheEntry, fmt.Errorf("cannot download %s: unexpected status %s", rawURL, res.Status)
+	}
+
+	raw, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, cacheEntry, fmt.Errorf("cannot download %s: %w", rawURL, err)
+	}
+
+	return raw, remoteDescriptorCacheEntry{
+		URL:          rawURL,
+		ETag:         res.Header.Get("ETag"),
+		LastModified: res.Header.Get("Last-Modified"),
+	}, nil
+}
+
+// readRemoteDescriptorCache returns a nil content if there is no valid cache entry.
+func readRemoteDescriptorCache(cachePath string) ([]byte, remoteDescriptorCacheEntry) {
+	entry := remoteDescriptorCacheEntry{}
+
+	rawEntry, err := ioutil.ReadFile(cachePath + ".json")
+	if err != nil {
+		return nil, entry
+	}
+	if err := json.Unmarshal(rawEntry, &entry); err != nil {
+		return nil, entry
+	}
+
+	raw, err := ioutil.ReadFile(cachePath + ".pb")
+	if err != nil {
+		return nil, entry
+	}
+	return raw, entry
+}
+
+func writeRemoteDescriptorCache(cachePath string, raw []byte, entry remoteDescriptorCacheEntry) error {
+	err := os.MkdirAll(filepath.Dir(cachePath), 0755)
+	if err != nil {
+		return err
+	}
+
+	rawEntry, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(cachePath+".pb", raw, 0644)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(cachePath+".json", rawEntry, 0644)
+}
@@ -0,0 +1,78 @@
+package core
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_fetchDescriptorSet(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	ctx := ctxInjectData(context.Background(), &contextData{Logger: logger})
+
+	requests := []*http.Request(nil)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write(rawProto)
+	}))
+	defer server.Close()
+
+	cacheDir := t.TempDir()
+
+	t.Run("download", func(t *testing.T) {
+		fileDescSet, err := fetchDescriptorSet(ctx, server.URL, cacheDir)
+		require.NoError(t, err)
+		assert.NotEmpty(t, fileDescSet.File)
+		require.Len(t, requests, 1)
+		assert.Empty(t, requests[0].Header.Get("If-None-Match"))
+	})
+
+	t.Run("revalidate", func(t *testing.T) {
+		fileDescSet, err := fetchDescriptorSet(ctx, server.URL, cacheDir)
+		require.NoError(t, err)
+		assert.NotEmpty(t, fileDescSet.File)
+		require.Len(t, requests, 2)
+		assert.Equal(t, `"v1"`, requests[1].Header.Get("If-None-Match"))
+	})
+
+	t.Run("offline", func(t *testing.T) {
+		url := server.URL
+		server.Close()
+
+		fileDescSet, err := fetchDescriptorSet(ctx, url, cacheDir)
+		require.NoError(t, err)
+		assert.NotEmpty(t, fileDescSet.File)
+
+		_, err = fetchDescriptorSet(ctx, url, t.TempDir())
+		assert.Error(t, err)
+	})
+
+	t.Run("file", func(t *testing.T) {
+		path, err := filepath.Abs(filepath.Join("testdata", "test.pb"))
+		require.NoError(t, err)
+
+		fileDescSet, err := fetchDescriptorSet(ctx, "file://"+path, cacheDir)
+		require.NoError(t, err)
+		assert.NotEmpty(t, fileDescSet.File)
+
+		fileDescSet, err = fetchDescriptorSet(ctx, "file://localhost"+path, cacheDir)
+		require.NoError(t, err)
+		assert.NotEmpty(t, fileDescSet.File)
+
+		_, err = fetchDescriptorSet(ctx, "file://testdata/test.pb", cacheDir)
+		assert.EqualError(t, err, "invalid descriptor url file://testdata/test.pb: file urls must be absolute, e.g. file:///path/to/descriptor.pb")
+	})
+}
@@ -0,0 +1,57 @@
+package core
+
+import (
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// typeResolver resolves types from the loaded descriptors.
+// Types that are not part of the loaded descriptors are looked up in the global registry.
+// It can be used to marshal and unmarshal google.protobuf.Any messages.
+type typeResolver struct {
+	files *protoregistry.Files
+}
+
+func newTypeResolver(files *protoregistry.Files) *typeResolver {
+	return &typeResolver{files: files}
+}
+
+func (r *typeResolver) FindMessageByName(name protoreflect.FullName) (protoreflect.MessageType, error) {
+	if r.files != nil {
+		desc, err := r.files.FindDescriptorByName(name)
+		if err == nil {
+			if messageDesc, ok := desc.(protoreflect.MessageDescriptor); ok {
+				return dynamicpb.NewMessageType(messageDesc), nil
+			}
+		}
+	}
+	return protoregistry.GlobalTypes.FindMessageByName(name)
+}
+
+func (r *typeResolver) FindMessageByURL(url string) (protoreflect.MessageType, error) {
+	// Type URL are in the form type.googleapis.com/package.Message
+	name := url
+	if i := strings.LastIndexByte(url, '/'); i >= 0 {
+		name = url[i+1:]
+	}
+	return r.FindMessageByName(protoreflect.FullName(name))
+}
+
+func (r *typeResolver) FindExtensionByName(field protoreflect.FullName) (protoreflect.ExtensionType, error) {
+	if r.files != nil {
+		desc, err := r.files.FindDescriptorByName(field)
+		if err == nil {
+			if extDesc, ok := desc.(protoreflect.ExtensionDescriptor); ok && extDesc.IsExtension() {
+				return dynamicpb.NewExtensionType(extDesc), nil
+			}
+		}
+	}
+	return protoregistry.GlobalTypes.FindExtensionByName(field)
+}
+
+func (r *typeResolver) FindExtensionByNumber(message protoreflect.FullName, field protoreflect.FieldNumber) (protoreflect.ExtensionType, error) {
+	return protoregistry.GlobalTypes.FindExtensionByNumber(message, field)
+}
@@ -0,0 +1,430 @@
+package core
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+
+	"github.com/jerome-quere/grpc-cli/internal/args"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func rpcRun(ctx context.Context, method protoreflect.MethodDescriptor) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, rawArgs []string) error {
+
+		// Client streaming methods read their requests from stdin or an input file,
+		// other methods build a single request from arguments.
+		var req *dynamicpb.Message
+		var reader *requestReader
+		if method.IsStreamingClient() {
+			if len(rawArgs) > 0 {
+				return withExitCode(ExitCodeInvalidArgs, fmt.Errorf("client streaming method does not accept args, requests are read from stdin or --input"))
+			}
+
+			input, err := cmd.Flags().GetString("input")
+			if err != nil {
+				return err
+			}
+			reader, err = newRequestReader(ctx, input, method.Input())
+			if err != nil {
+				return withExitCode(ExitCodeInvalidArgs, err)
+			}
+			defer reader.Close()
+		} else {
+			// Create gRPC request message
+			req = dynamicpb.NewMessage(method.Input())
+
+			// Request body is unmarshaled first so that args can override its fields
+			data, err := cmd.Flags().GetString("data")
+			if err != nil {
+				return err
+			}
+			if data != "" {
+				raw, err := readRequestData(ctx, data)
+				if err != nil {
+					return withExitCode(ExitCodeInvalidArgs, err)
+				}
+				err = unmarshalRequestData(ctx, raw, req)
+				if err != nil {
+					return withExitCode(ExitCodeInvalidArgs, err)
+				}
+			}
+
+			// Placeholders are resolved before args are parsed (e.g. id=${last.id})
+			rawArgs, err = interpolateArgs(ctx, rawArgs)
+			if err != nil {
+				return withExitCode(ExitCodeInvalidArgs, fmt.Errorf("cannot interpolate args: %w", err))
+			}
+
+			// Unmarshal argument inside the gRPC request message
+			err = args.UnmarshalOptions{Resolver: newTypeResolver(CtxFiles(ctx))}.Unmarshal(rawArgs, req)
+			if err != nil {
+				return withExitCode(ExitCodeInvalidArgs, fmt.Errorf("cannot unmarshal args: %s", err))
+			}
+		}
+
+		// Get the gRPC connection from the context.
+		// Connection will be automatically closed in the Bootstrap method.
+		conn, err := CtxGrpcConnection(ctx)
+		if err != nil {
+			return fmt.Errorf("cannot get grpc connection: %w", err)
+		}
+
+		// Injecting metadata in outgoing context
+		ctx := metadata.NewOutgoingContext(ctx, CtxMD(ctx))
+
+		// Executing gRPC call
+		md := &rpcMetadata{}
+		switch {
+		case method.IsStreamingClient() && method.IsStreamingServer():
+			err = invokeBidiStream(ctx, conn, method, reader, md)
+		case method.IsStreamingClient():
+			err = invokeClientStream(ctx, conn, method, reader, md)
+		case method.IsStreamingServer():
+			err = invokeServerStream(ctx, conn, method, req, md)
+		default:
+			err = invokeUnary(ctx, conn, method, req, md)
+		}
+
+		// Streaming calls only save their last response.
+		if lastResponse := ctxData(ctx).LastResponse; lastResponse != nil {
+			saveLastResponse(ctx, lastResponse)
+		}
+
+		// Failed calls print their status with decoded details.
+		if st, isStatus := statusFromError(err); isStatus {
+			printErr := printStatus(ctx, st)
+			if printErr != nil {
+				CtxLogger(ctx).Errorf("cannot print status: %s", printErr)
+			}
+		}
+
+		// Response metadata are printed even if the call failed as they often help to understand the failure.
+		printMD, flagErr := cmd.Flags().GetBool("print-metadata")
+		if flagErr == nil && printMD {
+			printMetadata(ctx, md)
+		}
+
+		return err
+	}
+}
+
+// rpcMetadata holds the headers and trailers received during a call.
+type rpcMetadata struct {
+	Header  metadata.MD
+	Trailer metadata.MD
+}
+
+// collectStreamMetadata stores headers and trailers of a stream in md.
+// The stream context is cancelled first so that reading headers never blocks on an unfinished stream.
+func collectStreamMetadata(stream grpc.ClientStream, cancel context.CancelFunc, md *rpcMetadata) {
+	cancel()
+	md.Header, _ = stream.Header()
+	md.Trailer = stream.Trailer()
+}
+
+// invokeUnary executes a unary gRPC call and print the response on stdout.
+func invokeUnary(ctx context.Context, conn *grpc.ClientConn, method protoreflect.MethodDescriptor, req proto.Message, md *rpcMetadata) error {
+	res := dynamicpb.NewMessage(method.Output())
+	err := conn.Invoke(ctx, fullMethodName(method), req, res, grpc.Header(&md.Header), grpc.Trailer(&md.Trailer))
+	if err != nil {
+		return fmt.Errorf("error while invoking rpc: %w", err)
+	}
+
+	return printMessage(ctx, res)
+}
+
+// invokeServerStream executes a server streaming gRPC call.
+// Every received message is printed on stdout as soon as it arrives.
+func invokeServerStream(ctx context.Context, conn *grpc.ClientConn, method protoreflect.MethodDescriptor, req proto.Message, md *rpcMetadata) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	streamDesc := &grpc.StreamDesc{
+		StreamName:    string(method.Name()),
+		ServerStreams: true,
+	}
+	stream, err := conn.NewStream(ctx, streamDesc, fullMethodName(method))
+	if err != nil {
+		return fmt.Errorf("error while opening stream: %w", err)
+	}
+	defer collectStreamMetadata(stream, cancel, md)
+
+	err = stream.SendMsg(req)
+	if err != nil {
+		return fmt.Errorf("error while sending request: %w", err)
+	}
+
+	err = stream.CloseSend()
+	if err != nil {
+		return fmt.Errorf("error while closing stream: %w", err)
+	}
+
+	for {
+		res := dynamicpb.NewMessage(method.Output())
+		err := stream.RecvMsg(res)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error while receiving response: %w", err)
+		}
+
+		err = printMessage(ctx, res)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// invokeClientStream executes a client streaming gRPC call.
+// Every request message returned by the reader is sent on the stream before waiting for the response.
+func invokeClientStream(ctx context.Context, conn *grpc.ClientConn, method protoreflect.MethodDescriptor, reader *requestReader, md *rpcMetadata) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	streamDesc := &grpc.StreamDesc{
+		StreamName:    string(method.Name()),
+		ClientStreams: true,
+	}
+	stream, err := conn.NewStream(ctx, streamDesc, fullMethodName(method))
+	if err != nil {
+		return fmt.Errorf("error while opening stream: %w", err)
+	}
+	defer collectStreamMetadata(stream, cancel, md)
+
+	for {
+		req, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		err = stream.SendMsg(req)
+		if err == io.EOF {
+			// Server ended the stream, the actual status is returned by RecvMsg.
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("error while sending request: %w", err)
+		}
+	}
+
+	err = stream.CloseSend()
+	if err != nil {
+		return fmt.Errorf("error while closing stream: %w", err)
+	}
+
+	res := dynamicpb.NewMessage(method.Output())
+	err = stream.RecvMsg(res)
+	if err != nil {
+		return fmt.Errorf("error while receiving response: %w", err)
+	}
+
+	return printMessage(ctx, res)
+}
+
+// invokeBidiStream executes a bidirectional streaming gRPC call.
+// Requests are sent as soon as they are read while responses are printed as they arrive.
+// A first interrupt signal half-closes the stream, a second one cancels it.
+func invokeBidiStream(ctx context.Context, conn *grpc.ClientConn, method protoreflect.MethodDescriptor, reader *requestReader, md *rpcMetadata) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	streamDesc := &grpc.StreamDesc{
+		StreamName:    string(method.Name()),
+		ClientStreams: true,
+		ServerStreams: true,
+	}
+	stream, err := conn.NewStream(ctx, streamDesc, fullMethodName(method))
+	if err != nil {
+		return fmt.Errorf("error while opening stream: %w", err)
+	}
+	defer collectStreamMetadata(stream, cancel, md)
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	// Requests are read in a dedicated goroutine so that a blocking read on stdin
+	// does not prevent us from handling responses and interrupts.
+	requests := make(chan *dynamicpb.Message)
+	readErrors := make(chan error, 1)
+	go func() {
+		for {
+			req, err := reader.Next()
+			if err != nil {
+				readErrors <- err
+				return
+			}
+			select {
+			case requests <- req:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// Responses are printed as soon as they arrive.
+	// The goroutine ends when the server closes the stream.
+	recvErrors := make(chan error, 1)
+	go func() {
+		for {
+			res := dynamicpb.NewMessage(method.Output())
+			err := stream.RecvMsg(res)
+			if err != nil {
+				recvErrors <- err
+				return
+			}
+			err = printMessage(ctx, res)
+			if err != nil {
+				recvErrors <- err
+				return
+			}
+		}
+	}()
+
+	// Once the stream is half-closed, pending channels are set to nil so we stop reading requests.
+	pendingRequests, pendingReadErrors := requests, readErrors
+	halfClosed := false
+	closeSend := func() error {
+		halfClosed = true
+		pendingRequests, pendingReadErrors = nil, nil
+		return stream.CloseSend()
+	}
+
+	for {
+		select {
+		case req := <-pendingRequests:
+			err := stream.SendMsg(req)
+			if err == io.EOF {
+				// Server ended the stream, the actual status is returned by RecvMsg.
+				err = closeSend()
+			}
+			if err != nil {
+				return fmt.Errorf("error while sending request: %w", err)
+			}
+
+		case err := <-pendingReadErrors:
+			if err != io.EOF {
+				return err
+			}
+			err = closeSend()
+			if err != nil {
+				return fmt.Errorf("error while closing stream: %w", err)
+			}
+
+		case <-interrupt:
+			if halfClosed {
+				CtxLogger(ctx).Infof("interrupted, cancelling stream")
+				cancel()
+				continue
+			}
+			CtxLogger(ctx).Infof("interrupted, closing stream. Interrupt again to cancel")
+			err := closeSend()
+			if err != nil {
+				return fmt.Errorf("error while closing stream: %w", err)
+			}
+
+		case err := <-recvErrors:
+			if err != io.EOF {
+				return fmt.Errorf("error while receiving response: %w", err)
+			}
+			CtxLogger(ctx).Infof("stream closed with status %s", codes.OK)
+			return nil
+		}
+	}
+}
+
+// fullMethodName returns the method name in the form expected by gRPC (/package.Service/Method).
+func fullMethodName(method protoreflect.MethodDescriptor) string {
+	service := method.Parent().(protoreflect.ServiceDescriptor)
+	return fmt.Sprintf("/%s/%s", service.FullName(), method.Name())
+}
+
+// flusher is implemented by buffered writers that can be flushed.
+type flusher interface {
+	Flush() error
+}
+
+// printMessage marshals a message in json and write it on stdout.
+// It is also saved so that the next calls can use it in placeholders.
+// Each message is followed by a new line and stdout is flushed when possible.
+func printMessage(ctx context.Context, message proto.Message) error {
+
+	// Marshal response in json
+	raw, err := protojson.MarshalOptions{
+		Multiline:       true,
+		Indent:          "  ",
+		UseProtoNames:   true,
+		EmitUnpopulated: true,
+		Resolver:        newTypeResolver(CtxFiles(ctx)),
+	}.Marshal(message)
+
+	if err != nil {
+		return fmt.Errorf("cannont marshal response: %s", err)
+	}
+
+	// Write response on stdout
+	stdout := CtxStdout(ctx)
+	_, err = stdout.Write(append(raw, '\n'))
+	if err != nil {
+		return fmt.Errorf("cannont write response: %s", err)
+	}
+
+	// Saved on disk once the call is finished (see rpcRun).
+	ctxData(ctx).LastResponse = raw
+
+	if f, ok := stdout.(flusher); ok {
+		err = f.Flush()
+		if err != nil {
+			return fmt.Errorf("cannot flush response: %s", err)
+		}
+	}
+
+	return nil
+}
+
+// printMetadata writes response headers and trailers on stderr.
+func printMetadata(ctx context.Context, md *rpcMetadata) {
+	stderr := CtxStderr(ctx)
+	for _, section := range []struct {
+		title string
+		md    metadata.MD
+	}{
+		{"Response headers:", md.Header},
+		{"Response trailers:", md.Trailer},
+	} {
+		fmt.Fprintln(stderr, section.title)
+
+		keys := make([]string, 0, len(section.md))
+		for key := range section.md {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			for _, value := range section.md[key] {
+				// Binary values are base64 encoded to keep the output readable.
+				if strings.HasSuffix(key, "-bin") {
+					value = base64.StdEncoding.EncodeToString([]byte(value))
+				}
+				fmt.Fprintf(stderr, "  %s: %s\n", key, value)
+			}
+		}
+	}
+}
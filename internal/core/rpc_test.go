@@ -0,0 +1,380 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// registerTestApi registers a test.Api service implementation on the given server.
+func registerTestApi(t *testing.T) func(server *grpc.Server) {
+	fileDescSet := descriptorpb.FileDescriptorSet{}
+	require.NoError(t, proto.Unmarshal(rawProto, &fileDescSet))
+	files, err := protodesc.NewFiles(&fileDescSet)
+	require.NoError(t, err)
+	desc, err := files.FindDescriptorByName("test.Simple")
+	require.NoError(t, err)
+	simple := desc.(protoreflect.MessageDescriptor)
+
+	return func(server *grpc.Server) {
+		server.RegisterService(&grpc.ServiceDesc{
+			ServiceName: "test.Api",
+			HandlerType: (*interface{})(nil),
+			Methods: []grpc.MethodDesc{
+				{
+					MethodName: "Echo",
+					Handler: func(_ interface{}, ctx context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+						req := dynamicpb.NewMessage(simple)
+						if err := dec(req); err != nil {
+							return nil, err
+						}
+						_ = grpc.SetHeader(ctx, metadata.Pairs("x-request-id", "42", "x-id-bin", "\x01\x02"))
+						_ = grpc.SetTrailer(ctx, metadata.Pairs("x-next-page", "2"))
+						// x-echo request metadata is sent back so tests can check what was sent
+						if md, exist := metadata.FromIncomingContext(ctx); exist && len(md.Get("x-echo")) > 0 {
+							_ = grpc.SetHeader(ctx, metadata.Pairs("x-echo", md.Get("x-echo")[0]))
+						}
+						if req.Get(simple.Fields().ByName("str")).String() == "error" {
+							return nil, testStatusError(t, req)
+						}
+						return req, nil
+					},
+				},
+			},
+			Streams: []grpc.StreamDesc{
+				{
+					StreamName:    "ServerStream",
+					ServerStreams: true,
+					Handler: func(_ interface{}, stream grpc.ServerStream) error {
+						req := dynamicpb.NewMessage(simple)
+						if err := stream.RecvMsg(req); err != nil {
+							return err
+						}
+						_ = stream.SetHeader(metadata.Pairs("x-request-id", "43"))
+						stream.SetTrailer(metadata.Pairs("x-count", "3"))
+						str := simple.Fields().ByName("str")
+						strs := req.Get(simple.Fields().ByName("strs")).List()
+						for i := 0; i < strs.Len(); i++ {
+							res := dynamicpb.NewMessage(simple)
+							res.Set(str, strs.Get(i))
+							if err := stream.SendMsg(res); err != nil {
+								return err
+							}
+						}
+						return nil
+					},
+				},
+				{
+					StreamName:    "ClientStream",
+					ClientStreams: true,
+					Handler: func(_ interface{}, stream grpc.ServerStream) error {
+						str := simple.Fields().ByName("str")
+						res := dynamicpb.NewMessage(simple)
+						strs := res.Mutable(simple.Fields().ByName("strs")).List()
+						for {
+							req := dynamicpb.NewMessage(simple)
+							err := stream.RecvMsg(req)
+							if err == io.EOF {
+								break
+							}
+							if err != nil {
+								return err
+							}
+							strs.Append(req.Get(str))
+						}
+						return stream.SendMsg(res)
+					},
+				},
+				{
+					StreamName:    "BidiStream",
+					ClientStreams: true,
+					ServerStreams: true,
+					Handler: func(_ interface{}, stream grpc.ServerStream) error {
+						for {
+							req := dynamicpb.NewMessage(simple)
+							err := stream.RecvMsg(req)
+							if err == io.EOF {
+								return nil
+							}
+							if err != nil {
+								return err
+							}
+							if err := stream.SendMsg(req); err != nil {
+								return err
+							}
+						}
+					},
+				},
+			},
+			Metadata: "test/test.proto",
+		}, struct{}{})
+	}
+}
+
+// testStatusError returns an error with details of well known and test types.
+func testStatusError(t *testing.T, req proto.Message) error {
+	st, err := status.New(codes.InvalidArgument, "invalid request").WithDetails(
+		&errdetails.BadRequest{
+			FieldViolations: []*errdetails.BadRequest_FieldViolation{
+				{Field: "str", Description: "must not be error"},
+			},
+		},
+		&errdetails.ErrorInfo{Reason: "INVALID_STR", Domain: "test"},
+	)
+	require.NoError(t, err)
+
+	rawReq, err := proto.Marshal(req)
+	require.NoError(t, err)
+
+	stProto := st.Proto()
+	stProto.Details = append(stProto.Details,
+		&anypb.Any{TypeUrl: "type.googleapis.com/test.Simple", Value: rawReq},
+		&anypb.Any{TypeUrl: "type.googleapis.com/test.Unknown", Value: []byte{0x01}},
+	)
+	return status.ErrorProto(stProto)
+}
+
+func TestRpc(t *testing.T) {
+
+	t.Run("unary", Test(&TestConfig{
+		Descriptor: rawProto,
+		Server:     registerTestApi(t),
+		Cmd:        "grpc-cli rpc test.Api Echo str=abc int32=32",
+		Check:      TestCheckGolden(),
+	}))
+
+	t.Run("data json", Test(&TestConfig{
+		Descriptor: rawProto,
+		Server:     registerTestApi(t),
+		Args:       []string{"grpc-cli", "rpc", "test.Api", "Echo", "-d", `{"str": "abc", "int32": 32, "strs": ["a", "b"]}`, "int32=64", "strs.1=c"},
+		Check:      TestCheckGolden(),
+	}))
+
+	t.Run("data clear", Test(&TestConfig{
+		Descriptor: rawProto,
+		Server:     registerTestApi(t),
+		Args:       []string{"grpc-cli", "rpc", "test.Api", "Echo", "-d", `{"str": "abc", "wrapper_int32": 32, "strs": ["a", "b"]}`, "wrapper_int32=null", "--", "-str"},
+		Check:      TestCheckGolden(),
+	}))
+
+	t.Run("interpolation", func(t *testing.T) {
+		require.NoError(t, os.Setenv("GRPC_CLI_TEST_STR", "from env"))
+		defer os.Unsetenv("GRPC_CLI_TEST_STR")
+		cacheDir := t.TempDir()
+
+		t.Run("env and vars", Test(&TestConfig{
+			Descriptor: rawProto,
+			Server:     registerTestApi(t),
+			CacheDir:   cacheDir,
+			Args:       []string{"grpc-cli", "--var", "count=32", "rpc", "test.Api", "Echo", "str=${GRPC_CLI_TEST_STR}", "int32=${count}", "strs=$${count}"},
+			Check:      TestCheckGolden(),
+		}))
+
+		t.Run("last response", Test(&TestConfig{
+			Descriptor: rawProto,
+			Server:     registerTestApi(t),
+			CacheDir:   cacheDir,
+			Args:       []string{"grpc-cli", "rpc", "test.Api", "Echo", "str={{ .last.str }} again", "int64=${last.int32}", "strs=${last.strs.0}"},
+			Check:      TestCheckGolden(),
+		}))
+
+		t.Run("undefined", Test(&TestConfig{
+			Descriptor: rawProto,
+			Server:     registerTestApi(t),
+			CacheDir:   cacheDir,
+			Args:       []string{"grpc-cli", "rpc", "test.Api", "Echo", "str=${GRPC_CLI_TEST_UNDEFINED}"},
+			Check:      TestCheckGolden(),
+		}))
+
+		t.Run("metadata", Test(&TestConfig{
+			Descriptor: rawProto,
+			Server:     registerTestApi(t),
+			CacheDir:   cacheDir,
+			Args:       []string{"grpc-cli", "-m", "x-echo: ${last.str}", "rpc", "test.Api", "Echo", "--print-metadata"},
+			Check:      TestCheckGolden(),
+		}))
+
+		t.Run("invalid last response", func(t *testing.T) {
+			cacheDir := t.TempDir()
+			require.NoError(t, ioutil.WriteFile(lastResponsePath(cacheDir), []byte(`{"str": `), 0600))
+
+			Test(&TestConfig{
+				Descriptor: rawProto,
+				Server:     registerTestApi(t),
+				CacheDir:   cacheDir,
+				Args:       []string{"grpc-cli", "rpc", "test.Api", "Echo", "str=abc"},
+				Check: func(t *testing.T, ctx *TestCheckFuncCtx) {
+					assert.Equal(t, ExitCodeSuccess, ctx.ExitCode)
+					assert.Contains(t, string(ctx.Stderr), "cannot load last response")
+					assert.Contains(t, string(ctx.Stdout), "abc")
+				},
+			})(t)
+
+			raw, err := ioutil.ReadFile(lastResponsePath(cacheDir))
+			require.NoError(t, err)
+			assert.True(t, json.Valid(raw))
+		})
+
+		t.Run("last streamed response", func(t *testing.T) {
+			cacheDir := t.TempDir()
+
+			Test(&TestConfig{
+				Descriptor: rawProto,
+				Server:     registerTestApi(t),
+				CacheDir:   cacheDir,
+				Cmd:        "grpc-cli rpc test.Api ServerStream strs.0=a strs.1=b strs.2=c",
+				Check:      TestCheckExitCode(ExitCodeSuccess),
+			})(t)
+
+			last, err := loadLastResponse(cacheDir)
+			require.NoError(t, err)
+			require.IsType(t, map[string]interface{}{}, last)
+			assert.Equal(t, "c", last.(map[string]interface{})["str"])
+		})
+	})
+
+	t.Run("data yaml file", Test(&TestConfig{
+		Descriptor: rawProto,
+		Server:     registerTestApi(t),
+		Cmd:        "grpc-cli rpc test.Api Echo --data @testdata/request.yaml",
+		Check:      TestCheckGolden(),
+	}))
+
+	t.Run("data stdin", Test(&TestConfig{
+		Descriptor: rawProto,
+		Server:     registerTestApi(t),
+		Cmd:        "grpc-cli rpc test.Api Echo -d @- kind_int32=1",
+		Stdin:      "kind_str: abc\n",
+		Check:      TestCheckGolden(),
+	}))
+
+	t.Run("data invalid", Test(&TestConfig{
+		Descriptor: rawProto,
+		Server:     registerTestApi(t),
+		Cmd:        "grpc-cli rpc test.Api Echo -d @-",
+		Stdin:      "unknown: abc\n",
+		Check:      TestCheckGolden(),
+	}))
+
+	t.Run("any", Test(&TestConfig{
+		Descriptor: rawProto,
+		Server:     registerTestApi(t),
+		Cmd:        "grpc-cli rpc test.Api Echo any.@type=test.Simple.Nested any.str=abc",
+		Check:      TestCheckGolden(),
+	}))
+
+	t.Run("server stream", Test(&TestConfig{
+		Descriptor: rawProto,
+		Server:     registerTestApi(t),
+		Cmd:        "grpc-cli rpc test.Api ServerStream strs.0=a strs.1=b strs.2=c",
+		Check:      TestCheckGolden(),
+	}))
+
+	t.Run("server stream empty", Test(&TestConfig{
+		Descriptor: rawProto,
+		Server:     registerTestApi(t),
+		Cmd:        "grpc-cli rpc test.Api ServerStream",
+		Check:      TestCheckGolden(),
+	}))
+
+	t.Run("client stream", Test(&TestConfig{
+		Descriptor: rawProto,
+		Server:     registerTestApi(t),
+		Cmd:        "grpc-cli rpc test.Api ClientStream",
+		Stdin:      "{\"str\": \"a\"}\n\n{\"str\": \"b\"}\n{\"str\": \"c\"}\n",
+		Check:      TestCheckGolden(),
+	}))
+
+	t.Run("client stream input file", Test(&TestConfig{
+		Descriptor: rawProto,
+		Server:     registerTestApi(t),
+		Cmd:        "grpc-cli rpc test.Api ClientStream --input testdata/client-stream.jsonl",
+		Check:      TestCheckGolden(),
+	}))
+
+	t.Run("client stream invalid json", Test(&TestConfig{
+		Descriptor: rawProto,
+		Server:     registerTestApi(t),
+		Cmd:        "grpc-cli rpc test.Api ClientStream",
+		Stdin:      "{\"str\": \"a\"}\n{\"unknown\": \"b\"}\n",
+		Check:      TestCheckGolden(),
+	}))
+
+	t.Run("bidi stream", Test(&TestConfig{
+		Descriptor: rawProto,
+		Server:     registerTestApi(t),
+		Cmd:        "grpc-cli rpc test.Api BidiStream",
+		Stdin:      "{\"str\": \"a\"}\nstr=b int32=2\n",
+		Check:      TestCheckGolden(),
+	}))
+
+	t.Run("bidi stream interpolation", Test(&TestConfig{
+		Descriptor: rawProto,
+		Server:     registerTestApi(t),
+		Cmd:        "grpc-cli --var count=3 rpc test.Api BidiStream",
+		Stdin:      "str=a int32=${count}\nstr=$${count}\n",
+		Check:      TestCheckGolden(),
+	}))
+
+	t.Run("bidi stream quoted args", Test(&TestConfig{
+		Descriptor: rawProto,
+		Server:     registerTestApi(t),
+		Cmd:        "grpc-cli rpc test.Api BidiStream",
+		Stdin:      "str='hello world' strs.0=\"it's \\\"a\\\" test\" strs.1=a\\ b\n",
+		Check:      TestCheckGolden(),
+	}))
+
+	t.Run("bidi stream unterminated quote", Test(&TestConfig{
+		Descriptor: rawProto,
+		Server:     registerTestApi(t),
+		Cmd:        "grpc-cli rpc test.Api BidiStream",
+		Stdin:      "str='hello world\n",
+		Check:      TestCheckGolden(),
+	}))
+
+	t.Run("bidi stream invalid args", Test(&TestConfig{
+		Descriptor: rawProto,
+		Server:     registerTestApi(t),
+		Cmd:        "grpc-cli rpc test.Api BidiStream",
+		Stdin:      "unknown=a\n",
+		Check:      TestCheckGolden(),
+	}))
+
+	t.Run("print metadata", Test(&TestConfig{
+		Descriptor: rawProto,
+		Server:     registerTestApi(t),
+		Cmd:        "grpc-cli rpc --print-metadata test.Api Echo str=abc",
+		Check:      TestCheckGolden(),
+	}))
+
+	t.Run("server stream print metadata", Test(&TestConfig{
+		Descriptor: rawProto,
+		Server:     registerTestApi(t),
+		Cmd:        "grpc-cli rpc test.Api ServerStream --print-metadata strs.0=a",
+		Check:      TestCheckGolden(),
+	}))
+
+	t.Run("error status", Test(&TestConfig{
+		Descriptor: rawProto,
+		Server:     registerTestApi(t),
+		Cmd:        "grpc-cli rpc test.Api Echo str=error int32=12",
+		Check:      TestCheckCombine(TestCheckExitCode(ExitCodeRpcOffset+int(codes.InvalidArgument)), TestCheckGolden()),
+	}))
+}
@@ -0,0 +1,65 @@
+package core
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"google.golang.org/genproto/googleapis/rpc/code"
+	_ "google.golang.org/genproto/googleapis/rpc/errdetails" // Register error details types
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// statusFromError extracts the gRPC status from err or any error it wraps.
+func statusFromError(err error) (*status.Status, bool) {
+	var grpcErr interface{ GRPCStatus() *status.Status }
+	if errors.As(err, &grpcErr) {
+		return grpcErr.GRPCStatus(), true
+	}
+	return nil, false
+}
+
+// printStatus writes a gRPC status in json on stderr.
+// Status details are decoded using the loaded descriptors and the well known google.rpc error details.
+func printStatus(ctx context.Context, st *status.Status) error {
+	marshalOptions := protojson.MarshalOptions{
+		UseProtoNames: true,
+		Resolver:      newTypeResolver(CtxFiles(ctx)),
+	}
+
+	details := []json.RawMessage(nil)
+	for _, detail := range st.Proto().GetDetails() {
+		raw, err := marshalOptions.Marshal(detail)
+		if err != nil {
+			// Detail type is unknown, we print the raw value.
+			CtxLogger(ctx).Debugf("cannot decode status detail %s: %s", detail.GetTypeUrl(), err)
+			raw, err = json.Marshal(map[string]string{
+				"@type": detail.GetTypeUrl(),
+				"value": base64.StdEncoding.EncodeToString(detail.GetValue()),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		details = append(details, raw)
+	}
+
+	raw, err := json.MarshalIndent(struct {
+		Code    string            `json:"code"`
+		Message string            `json:"message"`
+		Details []json.RawMessage `json:"details,omitempty"`
+	}{
+		Code:    code.Code(st.Code()).String(),
+		Message: st.Message(),
+		Details: details,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot marshal status: %s", err)
+	}
+
+	_, err = CtxStderr(ctx).Write(append(raw, '\n'))
+	return err
+}
@@ -6,6 +6,7 @@ import (
 	"flag"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"os"
 	"path"
 	"path/filepath"
@@ -15,6 +16,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
 )
 
 var (
@@ -24,9 +26,20 @@ var (
 type TestConfig struct {
 	Descriptor []byte
 
+	// If set, a gRPC server is started for the duration of the test.
+	// Services must be registered by this function.
+	// --target and --disable-tls flags are automatically added to the command.
+	Server func(server *grpc.Server)
+
 	Cmd  string
 	Args []string
 
+	// Stdin content of the command
+	Stdin string
+
+	// Directory where descriptors and call outputs are cached. A temporary directory is used by default.
+	CacheDir string
+
 	Check TestCheckFunc
 }
 
@@ -48,14 +61,35 @@ func Test(config *TestConfig) func(t *testing.T) {
 			args = strings.Split(config.Cmd, " ")
 		}
 
+		if config.Server != nil {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+
+			server := grpc.NewServer()
+			config.Server(server)
+			go server.Serve(listener) //nolint:errcheck
+			defer server.Stop()
+
+			// Flags are added right after the binary name as flag parsing stops on -h.
+			serverArgs := []string{args[0], "--target", listener.Addr().String(), "--disable-tls"}
+			args = append(serverArgs, args[1:]...)
+		}
+
+		cacheDir := config.CacheDir
+		if cacheDir == "" {
+			cacheDir = t.TempDir()
+		}
+
 		stderr := &bytes.Buffer{}
 		stdout := &bytes.Buffer{}
 
 		exitCode := Bootstrap(ctx, &BootstrapConfig{
 			Stderr:     stderr,
 			Stdout:     stdout,
+			Stdin:      strings.NewReader(config.Stdin),
 			Args:       args,
 			Descriptor: config.Descriptor,
+			CacheDir:   cacheDir,
 		})
 
 		config.Check(t, &TestCheckFuncCtx{
@@ -66,6 +100,22 @@ func Test(config *TestConfig) func(t *testing.T) {
 	}
 }
 
+// TestCheckCombine runs all the given checks
+func TestCheckCombine(checks ...TestCheckFunc) TestCheckFunc {
+	return func(t *testing.T, ctx *TestCheckFuncCtx) {
+		for _, check := range checks {
+			check(t, ctx)
+		}
+	}
+}
+
+// TestCheckExitCode assert the exit code of the command
+func TestCheckExitCode(expected int) TestCheckFunc {
+	return func(t *testing.T, ctx *TestCheckFuncCtx) {
+		assert.Equal(t, expected, ctx.ExitCode)
+	}
+}
+
 // TestCheckGolden assert stderr and stdout using golden
 func TestCheckGolden() TestCheckFunc {
 	return func(t *testing.T, ctx *TestCheckFuncCtx) {
@@ -114,6 +164,11 @@ func marshalGolden(t *testing.T, ctx *TestCheckFuncCtx) string {
 	str := buffer.String()
 	// Replace Windows return carriage.
 	str = strings.ReplaceAll(str, "\r", "")
+	// protojson randomly adds an extra space after ':' to prevent byte to byte comparison.
+	str = strings.ReplaceAll(str, "\":  ", "\": ")
+	// protojson errors prefix randomly uses a non-breaking space, logger may escape it.
+	str = strings.ReplaceAll(str, "proto:\u00a0", "proto: ")
+	str = strings.ReplaceAll(str, `proto:\u00a0`, "proto: ")
 	return str
 }
 
@@ -0,0 +1,214 @@
+package interpolate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// Data holds the values placeholders are resolved from.
+type Data struct {
+	// Env holds the environment variables.
+	Env map[string]string
+
+	// Vars holds the variables defined in the config profile.
+	Vars map[string]string
+
+	// Last is the JSON output of the previous call. It is nil if no call was made yet.
+	Last interface{}
+}
+
+// Escaped placeholders are written as is (e.g. $${HOME} => ${HOME}, {{{ .env.HOME }} => {{ .env.HOME }}).
+const (
+	escapedPlaceholder = "$${"
+	escapedTemplate    = "{{{"
+)
+
+// A variable name is an identifier optionally followed by a path (e.g. HOME, last.items.0.id).
+var variableRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*(\.[a-zA-Z0-9_-]+)*$`)
+
+// NewData returns data resolving placeholders from the environment, vars and the last call output.
+func NewData(vars map[string]string, last interface{}) Data {
+	env := map[string]string{}
+	for _, kv := range os.Environ() {
+		tmp := strings.SplitN(kv, "=", 2)
+		env[tmp[0]] = tmp[1]
+	}
+	return Data{Env: env, Vars: vars, Last: last}
+}
+
+// Interpolate replaces the placeholders of str. Resolved values are not interpolated again.
+// Supported placeholders are:
+// - ${NAME}: a profile variable or an environment variable
+// - ${env.NAME}, ${vars.NAME}, ${last.path.to.field}: a value from a single source
+// - {{ .env.NAME }}: a Go template executed with env, vars and last
+// A literal ${ is written $${ and a literal {{ is written {{{.
+func Interpolate(str string, data Data) (string, error) {
+	if !strings.Contains(str, "${") && !strings.Contains(str, "{{") {
+		return str, nil
+	}
+
+	res := strings.Builder{}
+	for i := 0; i < len(str); {
+		rest := str[i:]
+		switch {
+		case strings.HasPrefix(rest, escapedPlaceholder):
+			res.WriteString("${")
+			i += len(escapedPlaceholder)
+
+		case strings.HasPrefix(rest, escapedTemplate):
+			res.WriteString("{{")
+			i += len(escapedTemplate)
+
+		case strings.HasPrefix(rest, "${"):
+			end := strings.Index(rest, "}")
+			if end < 0 {
+				return "", fmt.Errorf("unterminated placeholder in %s", str)
+			}
+			value, err := data.resolve(rest[2:end])
+			if err != nil {
+				return "", err
+			}
+			res.WriteString(value)
+			i += end + 1
+
+		case strings.HasPrefix(rest, "{{"):
+			end := strings.Index(rest, "}}")
+			if end < 0 {
+				return "", fmt.Errorf("unterminated template in %s", str)
+			}
+			value, err := data.execute(rest[:end+2])
+			if err != nil {
+				return "", err
+			}
+			res.WriteString(value)
+			i += end + 2
+
+		default:
+			res.WriteByte(str[i])
+			i++
+		}
+	}
+	return res.String(), nil
+}
+
+// Escape escapes the placeholders of str so that Interpolate returns str unchanged.
+func Escape(str string) string {
+	res := strings.Builder{}
+	for i := 0; i < len(str); {
+		rest := str[i:]
+		switch {
+		case strings.HasPrefix(rest, "${"):
+			res.WriteString(escapedPlaceholder)
+			i += 2
+		case strings.HasPrefix(rest, "{{"):
+			res.WriteString(escapedTemplate)
+			i += 2
+		default:
+			res.WriteByte(str[i])
+			i++
+		}
+	}
+	return res.String()
+}
+
+// resolve returns the value of a ${name} placeholder.
+func (d Data) resolve(name string) (string, error) {
+	if !variableRegexp.MatchString(name) {
+		return "", fmt.Errorf("invalid variable name %s", name)
+	}
+
+	words := strings.Split(name, ".")
+	if len(words) == 1 {
+		if value, exist := d.Vars[name]; exist {
+			return value, nil
+		}
+		if value, exist := d.Env[name]; exist {
+			return value, nil
+		}
+		return "", fmt.Errorf("undefined variable %s", name)
+	}
+
+	value, exist := lookup(d.templateData(), words)
+	if !exist {
+		return "", fmt.Errorf("undefined variable %s", name)
+	}
+	return format(value)
+}
+
+// execute executes a {{ }} template.
+func (d Data) execute(text string) (string, error) {
+	tmpl, err := template.New("").Option("missingkey=error").Funcs(template.FuncMap{
+		"json": func(value interface{}) (string, error) {
+			raw, err := json.Marshal(value)
+			return string(raw), err
+		},
+	}).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid template %s: %w", text, err)
+	}
+
+	res := bytes.Buffer{}
+	err = tmpl.Execute(&res, d.templateData())
+	if err != nil {
+		return "", fmt.Errorf("cannot execute template %s: %w", text, err)
+	}
+	return res.String(), nil
+}
+
+func (d Data) templateData() map[string]interface{} {
+	return map[string]interface{}{
+		"env":  d.Env,
+		"vars": d.Vars,
+		"last": d.Last,
+	}
+}
+
+// lookup returns the value at the given path. Lists are indexed by position (e.g. items.0.id).
+func lookup(value interface{}, path []string) (interface{}, bool) {
+	for _, word := range path {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			child, exist := v[word]
+			if !exist {
+				return nil, false
+			}
+			value = child
+		case map[string]string:
+			child, exist := v[word]
+			if !exist {
+				return nil, false
+			}
+			value = child
+		case []interface{}:
+			index, err := strconv.Atoi(word)
+			if err != nil || index < 0 || index >= len(v) {
+				return nil, false
+			}
+			value = v[index]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// format returns a resolved value as a string. Objects and lists are written in JSON so they can be used as inline args.
+func format(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case nil:
+		return "null", nil
+	case map[string]interface{}, []interface{}, map[string]string:
+		raw, err := json.Marshal(v)
+		return string(raw), err
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
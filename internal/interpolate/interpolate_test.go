@@ -0,0 +1,92 @@
+package interpolate
+
+import (
+	"encoding/json"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterpolate(t *testing.T) {
+	var last interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{"id": "abc", "count": 3, "items": [{"name": "a"}], "nested": {"ok": true}, "empty": null}`), &last))
+	data := Data{
+		Env:  map[string]string{"HOME": "/home/test", "TOKEN": "env-token"},
+		Vars: map[string]string{"TOKEN": "var-token", "org": "acme"},
+		Last: last,
+	}
+
+	tests := []struct {
+		str      string
+		expected string
+	}{
+		{"no placeholder", "no placeholder"},
+		{"${HOME}/file", "/home/test/file"},
+		{"Bearer ${TOKEN}", "Bearer var-token"},
+		{"${env.TOKEN}", "env-token"},
+		{"${vars.org}-${org}", "acme-acme"},
+		{"id=${last.id}", "id=abc"},
+		{"${last.count}", "3"},
+		{"${last.items.0.name}", "a"},
+		{"nested=${last.nested}", `nested={"ok":true}`},
+		{"${last.empty}", "null"},
+		{"$${HOME}", "${HOME}"},
+		{"{{{ .env.HOME }}", "{{ .env.HOME }}"},
+		{"$$${HOME}", "$${HOME}"},
+		{"{{ .env.HOME }}", "/home/test"},
+		{"{{ .vars.org }}/{{ .last.id }}", "acme/abc"},
+		{"{{ json .last.nested }}", `{"ok":true}`},
+		{`{"a":{"b":1}}`, `{"a":{"b":1}}`},
+		// Resolved values are not interpolated again
+		{"${last.id}{{ \"${HOME}\" }}", "abc${HOME}"},
+	}
+	for _, test := range tests {
+		t.Run(test.str, func(t *testing.T) {
+			actual, err := Interpolate(test.str, data)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+
+	errTests := []struct {
+		str      string
+		expected string
+	}{
+		{"${UNKNOWN}", "undefined variable UNKNOWN"},
+		{"${last.unknown}", "undefined variable last.unknown"},
+		{"${last.items.1}", "undefined variable last.items.1"},
+		{"${HOME", "unterminated placeholder in ${HOME"},
+		{"${a b}", "invalid variable name a b"},
+		{"{{ .env.HOME", "unterminated template in {{ .env.HOME"},
+	}
+	for _, test := range errTests {
+		t.Run(test.str, func(t *testing.T) {
+			_, err := Interpolate(test.str, data)
+			assert.EqualError(t, err, test.expected)
+		})
+	}
+
+	_, err := Interpolate("{{ .env.UNKNOWN }}", data)
+	assert.Error(t, err)
+	_, err = Interpolate("{{ .last.id }}", Data{})
+	assert.Error(t, err)
+}
+
+// TestEscape checks that escaped strings are interpolated back to themselves.
+func TestEscape(t *testing.T) {
+	alphabet := []byte("${}a ")
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		b := make([]byte, r.Intn(10))
+		for j := range b {
+			b[j] = alphabet[r.Intn(len(alphabet))]
+		}
+		str := string(b)
+
+		res, err := Interpolate(Escape(str), Data{})
+		require.NoError(t, err, "escaped: %q", Escape(str))
+		assert.Equal(t, str, res, "escaped: %q", Escape(str))
+	}
+}